@@ -42,6 +42,7 @@ func main() {
 				Comment:  col.Comment,
 				DataType: col.DataType,
 				Size:     col.Size,
+				Scale:    col.Scale,
 				Values:   col.Values,
 			})
 		}
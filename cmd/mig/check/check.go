@@ -0,0 +1,133 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+
+	"github.com/titpetric/cli"
+
+	"github.com/go-bridget/mig/cmd/mig/internal/configfile"
+	"github.com/go-bridget/mig/db"
+	"github.com/go-bridget/mig/migrate"
+)
+
+// Name is the command title.
+const Name = "Check database is up to date with migrations"
+
+// Exit codes for check results. Failed takes precedence over ahead,
+// and ahead over pending, when files are in several states.
+const (
+	ExitPending = 2
+	ExitFailed  = 3
+	ExitAhead   = 4
+)
+
+// exitError is a check failure with a process exit code.
+type exitError struct {
+	code    int
+	message string
+}
+
+func (e *exitError) Error() string {
+	return e.message
+}
+
+// ExitCode returns the process exit code.
+func (e *exitError) ExitCode() int {
+	return e.code
+}
+
+// New creates a new check command.
+func New() *cli.Command {
+	var config struct {
+		db      *db.Options
+		migrate *migrate.Options
+		file    *configfile.Options
+	}
+
+	return &cli.Command{
+		Name:  "check",
+		Title: Name,
+		Bind: func(fs *cli.FlagSet) {
+			config.db = db.NewOptions()
+			config.db.Bind(fs)
+			config.migrate = migrate.NewOptions()
+			config.migrate.Bind(fs)
+			config.file = configfile.Bind(fs)
+		},
+		Run: func(ctx context.Context, args []string) error {
+			if len(args) > 0 {
+				config.migrate.Project = args[0]
+			}
+
+			if err := config.file.Apply("check", config.migrate.Project); err != nil {
+				return err
+			}
+
+			if config.migrate.Project == "" {
+				return errors.New("Specify project name as first argument to check")
+			}
+
+			if err := migrate.Load(config.migrate); err != nil {
+				return fmt.Errorf("error loading migrations: %w", err)
+			}
+
+			handle, err := db.ConnectWithRetry(ctx, config.db)
+			if err != nil {
+				return errors.Wrap(err, "error connecting to database")
+			}
+
+			fs, err := migrate.Loaded(config.migrate.Project)
+			if err != nil {
+				return err
+			}
+
+			files, err := migrate.Pending(ctx, handle, fs, config.migrate)
+			if err != nil {
+				return err
+			}
+
+			if len(files) == 0 {
+				fmt.Printf("OK: %d migrations applied\n", len(fs.Migrations()))
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "FILENAME\tSTATE\tSTATEMENTS")
+			for _, file := range files {
+				fmt.Fprintf(w, "%s\t%s\t%d/%d\n", file.Filename, strings.ToUpper(file.State), file.Applied, file.Statements)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			return result(files)
+		},
+	}
+}
+
+// result returns the exit error for files that aren't applied.
+func result(files []*migrate.FileStatus) error {
+	counts := map[string]int{}
+	for _, file := range files {
+		state := file.State
+		if state == migrate.StatePartial {
+			state = migrate.StateFailed
+		}
+		counts[state]++
+	}
+
+	switch {
+	case counts[migrate.StateFailed] > 0:
+		return &exitError{ExitFailed, fmt.Sprintf("%d failed migrations", counts[migrate.StateFailed])}
+	case counts[migrate.StateAhead] > 0:
+		return &exitError{ExitAhead, fmt.Sprintf("database is ahead, %d applied migrations are unknown", counts[migrate.StateAhead])}
+	default:
+		return &exitError{ExitPending, fmt.Sprintf("%d pending migrations", counts[migrate.StatePending])}
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/titpetric/cli"
 
+	"github.com/go-bridget/mig/cmd/mig/internal"
+	"github.com/go-bridget/mig/cmd/mig/internal/configfile"
 	"github.com/go-bridget/mig/db"
 	"github.com/go-bridget/mig/migrate"
 )
@@ -15,20 +17,12 @@ import (
 // Name is the command title.
 const Name = "Create database schema SQL"
 
-func createDatabaseQuery(driver, name string) string {
-	switch driver {
-	case "pgx":
-		return fmt.Sprintf(`CREATE DATABASE "%s"`, name)
-	default:
-		return fmt.Sprintf("CREATE DATABASE `%s`", name)
-	}
-}
-
 // New creates a new create command.
 func New() *cli.Command {
 	var config struct {
 		db      *db.Options
 		migrate *migrate.Options
+		file    *configfile.Options
 	}
 
 	return &cli.Command{
@@ -39,18 +33,23 @@ func New() *cli.Command {
 			config.db.Bind(fs)
 			config.migrate = migrate.NewOptions()
 			config.migrate.Bind(fs)
+			config.file = configfile.Bind(fs)
 		},
 		Run: func(ctx context.Context, args []string) error {
 			if len(args) > 0 {
 				config.migrate.Project = args[0]
 			}
 
+			if err := config.file.Apply("create", config.migrate.Project); err != nil {
+				return err
+			}
+
 			if config.migrate.Project == "" {
 				return errors.Errorf("Specify project name as first argument to create")
 			}
 
 			driver, _ := db.ParseDSN(config.db.Credentials.DSN)
-			query := createDatabaseQuery(driver, config.migrate.Project)
+			query := internal.CreateDatabaseQuery(driver, config.migrate.Project)
 
 			if config.migrate.Apply {
 				handle, err := db.ConnectWithRetry(ctx, config.db)
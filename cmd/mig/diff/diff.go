@@ -0,0 +1,136 @@
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/titpetric/cli"
+
+	"github.com/go-bridget/mig/cmd/mig/internal"
+	"github.com/go-bridget/mig/cmd/mig/internal/configfile"
+	"github.com/go-bridget/mig/db"
+	"github.com/go-bridget/mig/migrate"
+	"github.com/go-bridget/mig/model"
+)
+
+// Name is the command title.
+const Name = "Compare schemas of databases or schema files"
+
+// New creates a new diff command.
+func New() *cli.Command {
+	var config struct {
+		file *configfile.Options
+
+		from   string
+		to     string
+		format string
+		driver string
+	}
+
+	return &cli.Command{
+		Name:  "diff",
+		Title: Name,
+		Bind: func(fs *cli.FlagSet) {
+			config.file = configfile.Bind(fs)
+
+			fs.StringVar(&config.from, "from", "", "Source schema, a DSN or a schema file (YAML or JSON)")
+			fs.StringVar(&config.to, "to", "", "Target schema, a DSN or a schema file (YAML or JSON)")
+			fs.StringVar(&config.format, "format", "text", "Output format: text, json or sql")
+			fs.StringVar(&config.driver, "driver", "", "Driver for sql output (mysql, postgres, sqlite), defaults to the driver of --from")
+		},
+		Run: func(ctx context.Context, args []string) error {
+			if err := config.file.Apply("diff", ""); err != nil {
+				return err
+			}
+
+			if config.from == "" || config.to == "" {
+				return errors.New("Specify the schemas to compare with --from and --to")
+			}
+
+			from, fromDriver, err := load(ctx, config.from)
+			if err != nil {
+				return errors.Wrap(err, "error reading --from schema")
+			}
+			to, toDriver, err := load(ctx, config.to)
+			if err != nil {
+				return errors.Wrap(err, "error reading --to schema")
+			}
+
+			diff := model.Compare(from, to)
+
+			switch config.format {
+			case "text":
+				if diff.Empty() {
+					fmt.Println("No differences")
+					return nil
+				}
+				fmt.Print(diff)
+				return nil
+			case "json":
+				data, err := json.MarshalIndent(diff, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			case "sql":
+				driver := config.driver
+				for _, name := range []string{fromDriver, toDriver} {
+					if driver == "" {
+						driver = name
+					}
+				}
+				if driver == "" {
+					return errors.New("Specify the driver for sql output with --driver")
+				}
+
+				stmts, err := migrate.Plan(diff, driver)
+				if err != nil {
+					return err
+				}
+				for _, stmt := range stmts {
+					fmt.Printf("%s;\n\n", stmt)
+				}
+				return nil
+			}
+			return errors.Errorf("unknown format: %s", config.format)
+		},
+	}
+}
+
+// load reads a schema file, or introspects the database for a DSN.
+// The driver name is empty for schema files. Databases are connected
+// to once, without retries, so a wrong DSN fails fast.
+func load(ctx context.Context, source string) ([]*model.Table, string, error) {
+	if isSchemaFile(source) {
+		tables, err := internal.ReadSchema(source)
+		return tables, "", err
+	}
+
+	options := db.NewOptions()
+	options.Credentials.DSN = source
+
+	handle, err := db.ConnectWithOptions(ctx, options)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "error connecting to database")
+	}
+	defer handle.Close()
+
+	return internal.Describe(ctx, handle)
+}
+
+// isSchemaFile returns true for existing files with a YAML or JSON extension.
+func isSchemaFile(source string) bool {
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".yaml", ".yml", ".json":
+		info, err := os.Stat(source)
+		return err == nil && !info.IsDir()
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package diff
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	flag "github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// createDB creates a sqlite database file with the statements.
+func createDB(t *testing.T, stmts ...string) string {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), "schema.db")
+	handle, err := sql.Open("sqlite", filename)
+	require.NoError(t, err)
+	defer handle.Close()
+
+	for _, stmt := range stmts {
+		_, err := handle.Exec(stmt)
+		require.NoError(t, err, stmt)
+	}
+	return "sqlite://" + filename
+}
+
+// run runs the diff command with args, and returns the printed output.
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	command := New()
+	fs := flag.NewFlagSet(command.Name, flag.ContinueOnError)
+	command.Bind(fs)
+	require.NoError(t, fs.Parse(args))
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	runErr := command.Run(context.Background(), fs.Args())
+	w.Close()
+
+	output, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(output), runErr
+}
+
+func TestDiff(t *testing.T) {
+	from := createDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE TABLE sessions (id INTEGER)",
+	)
+	to := createDB(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT)",
+		"CREATE INDEX users_email ON users (email)",
+	)
+
+	output, err := run(t, "--from", from, "--to", to)
+	require.NoError(t, err)
+	require.Equal(t, "- table sessions\n~ table users\n  + column email varchar\n  + index users_email (email)\n", output)
+
+	output, err = run(t, "--from", from, "--to", to, "--format", "sql")
+	require.NoError(t, err)
+	require.Equal(t, `ALTER TABLE "users" ADD COLUMN "email" TEXT;
+
+CREATE INDEX "users_email" ON "users" ("email");
+
+DROP TABLE "sessions";
+
+`, output)
+
+	output, err = run(t, "--from", to, "--to", to)
+	require.NoError(t, err)
+	require.Equal(t, "No differences\n", output)
+
+	// Databases are connected to once, without retries
+	missing := "sqlite://" + filepath.Join(t.TempDir(), "missing", "schema.db")
+	_, err = run(t, "--from", missing, "--to", to)
+	require.ErrorContains(t, err, "error connecting to database")
+
+	_, err = run(t, "--from", from)
+	require.ErrorContains(t, err, "--from and --to")
+
+	_, err = run(t, "--from", from, "--to", to, "--format", "xml")
+	require.ErrorContains(t, err, "unknown format: xml")
+}
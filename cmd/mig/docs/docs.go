@@ -5,6 +5,7 @@ import (
 
 	"github.com/titpetric/cli"
 
+	"github.com/go-bridget/mig/cmd/mig/internal/configfile"
 	"github.com/go-bridget/mig/db"
 	"github.com/go-bridget/mig/db/introspect"
 )
@@ -15,7 +16,8 @@ const Name = "Generate markdown docs from DB schema"
 // New creates a new docs command.
 func New() *cli.Command {
 	var config struct {
-		db *db.Options
+		db   *db.Options
+		file *configfile.Options
 
 		output   string
 		filename string
@@ -29,6 +31,7 @@ func New() *cli.Command {
 		Bind: func(fs *cli.FlagSet) {
 			config.db = db.NewOptions()
 			config.db.Bind(fs)
+			config.file = configfile.Bind(fs)
 
 			fs.StringVar(&config.output, "output", "docs", "Output folder where to generate docs")
 			fs.StringVar(&config.filename, "output-file", "", "Output as single filename")
@@ -36,6 +39,10 @@ func New() *cli.Command {
 			fs.BoolVar(&config.jsonOut, "json", false, "Output as JSON")
 		},
 		Run: func(ctx context.Context, args []string) error {
+			if err := config.file.Apply("docs", ""); err != nil {
+				return err
+			}
+
 			handle, err := db.ConnectWithRetry(ctx, config.db)
 			if err != nil {
 				return err
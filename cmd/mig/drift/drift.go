@@ -0,0 +1,131 @@
+package drift
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+
+	"github.com/pkg/errors"
+
+	"github.com/titpetric/cli"
+
+	"github.com/go-bridget/mig/cmd/mig/internal"
+	"github.com/go-bridget/mig/cmd/mig/internal/configfile"
+	"github.com/go-bridget/mig/db"
+	"github.com/go-bridget/mig/migrate"
+	"github.com/go-bridget/mig/model"
+)
+
+// Name is the command title.
+const Name = "Compare a database with the schema from its migrations"
+
+// New creates a new drift command.
+func New() *cli.Command {
+	var config struct {
+		db      *db.Options
+		migrate *migrate.Options
+		file    *configfile.Options
+
+		scratch     string
+		format      string
+		ignoreExtra bool
+	}
+
+	return &cli.Command{
+		Name:  "drift",
+		Title: Name,
+		Bind: func(fs *cli.FlagSet) {
+			config.db = db.NewOptions()
+			config.db.Bind(fs)
+			config.migrate = migrate.NewOptions()
+			config.migrate.Bind(fs)
+			config.file = configfile.Bind(fs)
+
+			fs.StringVar(&config.scratch, "scratch-dsn", "", "Server for the scratch database (mysql, postgres), defaults to the database DSN")
+			fs.StringVar(&config.format, "format", "text", "Output format: text or json")
+			fs.BoolVar(&config.ignoreExtra, "ignore-extra", false, "Ignore tables that only exist in the database, e.g. from other projects")
+		},
+		Run: func(ctx context.Context, args []string) error {
+			if len(args) > 0 {
+				config.migrate.Project = args[0]
+			}
+
+			if err := config.file.Apply("drift", config.migrate.Project); err != nil {
+				return err
+			}
+
+			if config.migrate.Project == "" {
+				return errors.New("Specify project name as first argument to drift")
+			}
+			if config.format != "text" && config.format != "json" {
+				return errors.Errorf("unknown format: %s", config.format)
+			}
+
+			if err := migrate.Load(config.migrate); err != nil {
+				return fmt.Errorf("error loading migrations: %w", err)
+			}
+
+			fs, err := migrate.Loaded(config.migrate.Project)
+			if err != nil {
+				return err
+			}
+
+			handle, err := db.ConnectWithRetry(ctx, config.db)
+			if err != nil {
+				return errors.Wrap(err, "error connecting to database")
+			}
+			defer handle.Close()
+
+			scratch, cleanup, err := internal.ScratchDB(ctx, config.db, config.scratch, internal.ScratchName("drift", config.migrate.Project))
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			// Progress of the scratch migrations is only logged with --verbose.
+			options := *config.migrate
+			if !options.Verbose {
+				options.Logger = log.New(io.Discard, "", 0)
+			}
+
+			diff, err := migrate.Drift(ctx, handle, scratch, fs, &options)
+			if err != nil {
+				return err
+			}
+
+			filter(diff)
+			if config.ignoreExtra {
+				diff.Added = nil
+			}
+
+			if config.format == "json" {
+				data, err := json.MarshalIndent(diff, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			} else if diff.Empty() {
+				fmt.Println("No drift")
+			} else {
+				fmt.Print(diff)
+			}
+
+			if !diff.Empty() {
+				return errors.Errorf("schema drift found for project %s", config.migrate.Project)
+			}
+			return nil
+		},
+	}
+}
+
+// filter removes the bookkeeping tables and ignored tables from diff.
+func filter(diff *model.Diff) {
+	diff.Added = internal.SchemaTables(diff.Added)
+	diff.Removed = internal.SchemaTables(diff.Removed)
+	diff.Changed = slices.DeleteFunc(diff.Changed, func(table *model.TableDiff) bool {
+		return table.From.Ignore() || table.To.Ignore()
+	})
+}
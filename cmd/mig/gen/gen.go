@@ -9,6 +9,7 @@ import (
 
 	"github.com/titpetric/cli"
 
+	"github.com/go-bridget/mig/cmd/mig/internal/configfile"
 	"github.com/go-bridget/mig/db"
 	"github.com/go-bridget/mig/db/introspect"
 	"github.com/go-bridget/mig/model"
@@ -21,6 +22,7 @@ const Name = "Generate source code from DB schema"
 func New() *cli.Command {
 	var config struct {
 		db      *db.Options
+		file    *configfile.Options
 		options Options
 	}
 	config.options.Language = "go"
@@ -32,6 +34,7 @@ func New() *cli.Command {
 		Bind: func(fs *cli.FlagSet) {
 			config.db = db.NewOptions()
 			config.db.Bind(fs)
+			config.file = configfile.Bind(fs)
 
 			fs.StringVar(&config.options.Language, "lang", "go", "Programming language")
 			fs.StringVar(&config.options.Output, "output", "model", "Output folder where to generate types")
@@ -40,6 +43,10 @@ func New() *cli.Command {
 			fs.BoolVar(&config.options.Go.SkipJSON, "go.skip-json", false, "Skip JSON tags (go)")
 		},
 		Run: func(ctx context.Context, args []string) error {
+			if err := config.file.Apply("gen", ""); err != nil {
+				return err
+			}
+
 			handle, err := db.ConnectWithRetry(ctx, config.db)
 			if err != nil {
 				return err
@@ -0,0 +1,204 @@
+// Package configfile applies defaults from a `mig.yaml` project configuration
+// file to command flags.
+package configfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFilename is the configuration file read if `--config` isn't set.
+const DefaultFilename = "mig.yaml"
+
+// Config holds the contents of a `mig.yaml` file.
+//
+//	projects:
+//	  stats:
+//	    path: schema/stats
+//	environments:
+//	  dev:
+//	    dsn: sqlite://dev.db
+//	  prod:
+//	    dsn: ${PROD_DSN}
+//	commands:
+//	  lint:
+//	    skip-comments: true
+type Config struct {
+	// Projects maps project names to their settings.
+	Projects map[string]*Project `yaml:"projects"`
+
+	// Environments maps environment names, like dev or prod, to their settings.
+	Environments map[string]*Environment `yaml:"environments"`
+
+	// Commands maps command names to flag defaults, keyed by flag name.
+	Commands map[string]map[string]Values `yaml:"commands"`
+}
+
+// Project holds the settings for a project.
+type Project struct {
+	// Path is the directory with the migrations of the project.
+	Path string `yaml:"path"`
+}
+
+// Environment holds the settings for an environment.
+type Environment struct {
+	// DSN holds one or more database DSNs. Environment variables
+	// are expanded, e.g. `${PROD_DSN}`.
+	DSN Values `yaml:"dsn"`
+}
+
+// Values holds one or more flag values. In YAML, it's either
+// a scalar or a list of scalars.
+type Values []string
+
+// UnmarshalYAML decodes a scalar or a list of scalars.
+func (v *Values) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*v = Values{node.Value}
+		return nil
+	}
+
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*v = list
+	return nil
+}
+
+// Options include configuration file options.
+type Options struct {
+	// Filename is the configuration file, `mig.yaml` by default.
+	Filename string
+
+	// Env selects an environment from the configuration file.
+	Env string
+
+	fs *flag.FlagSet
+}
+
+// Bind registers the `--config` and `--env` flags on the given FlagSet.
+// The FlagSet is kept to apply the configuration with Apply.
+func Bind(fs *flag.FlagSet) *Options {
+	options := &Options{
+		Filename: DefaultFilename,
+		Env:      os.Getenv("MIG_ENV"),
+		fs:       fs,
+	}
+	fs.StringVar(&options.Filename, "config", options.Filename, "Project configuration file")
+	fs.StringVar(&options.Env, "env", options.Env, "Environment from the configuration file (e.g. dev, staging, prod)")
+	return options
+}
+
+// Load reads the configuration file. A missing default configuration
+// file results in an empty configuration.
+func (options *Options) Load() (*Config, error) {
+	result := &Config{}
+
+	contents, err := os.ReadFile(options.Filename)
+	if errors.Is(err, fs.ErrNotExist) && !options.fs.Changed("config") {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(contents, result); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", options.Filename, err)
+	}
+	return result, nil
+}
+
+// Apply sets flags for a command and project from the configuration file.
+// Flags passed on the command line take precedence over the file. The
+// project may be empty, and flags the command doesn't have are ignored,
+// except for the command defaults, where they're reported as errors.
+func (options *Options) Apply(command, project string) error {
+	config, err := options.Load()
+	if err != nil {
+		return err
+	}
+
+	if options.Env != "" {
+		env, ok := config.Environments[options.Env]
+		if !ok {
+			return fmt.Errorf("environment '%s' isn't defined in %s", options.Env, options.Filename)
+		}
+
+		dsn, err := expand(env.DSN)
+		if err != nil {
+			return fmt.Errorf("environment '%s': %w", options.Env, err)
+		}
+		if err := options.set("db-dsn", dsn); err != nil {
+			return fmt.Errorf("environment '%s': %w", options.Env, err)
+		}
+	}
+
+	if p, ok := config.Projects[project]; ok && p.Path != "" {
+		if err := options.set("path", Values{p.Path}); err != nil {
+			return fmt.Errorf("project '%s': %w", project, err)
+		}
+	}
+
+	defaults := config.Commands[command]
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if options.fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown flag '%s' for command %s in %s", name, command, options.Filename)
+		}
+		if err := options.set(name, defaults[name]); err != nil {
+			return fmt.Errorf("command %s: %w", command, err)
+		}
+	}
+	return nil
+}
+
+// set sets a flag if it exists and wasn't passed on the command line.
+// Only flags that may be repeated accept more than one value.
+func (options *Options) set(name string, values Values) error {
+	f := options.fs.Lookup(name)
+	if f == nil || f.Changed || len(values) == 0 {
+		return nil
+	}
+
+	if len(values) > 1 && f.Value.Type() != "stringArray" && f.Value.Type() != "stringSlice" {
+		return fmt.Errorf("flag '%s' takes a single value", name)
+	}
+	for _, value := range values {
+		if err := options.fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value '%s' for flag '%s': %w", value, name, err)
+		}
+	}
+	return nil
+}
+
+// expand replaces environment variable references in values.
+// Referencing an unset variable is an error.
+func expand(values Values) (Values, error) {
+	var missing []string
+	result := make(Values, len(values))
+	for i, value := range values {
+		result[i] = os.Expand(value, func(name string) string {
+			v, ok := os.LookupEnv(name)
+			if !ok {
+				missing = append(missing, name)
+			}
+			return v
+		})
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("environment variable %s isn't set", missing[0])
+	}
+	return result, nil
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/go-bridget/mig/db"
+)
+
+// CreateDatabaseQuery returns the query creating the database name.
+func CreateDatabaseQuery(driver, name string) string {
+	switch driver {
+	case "pgx":
+		return fmt.Sprintf(`CREATE DATABASE "%s"`, name)
+	default:
+		return fmt.Sprintf("CREATE DATABASE `%s`", name)
+	}
+}
+
+// DropDatabaseQuery returns the query dropping the database name.
+func DropDatabaseQuery(driver, name string) string {
+	switch driver {
+	case "pgx":
+		return fmt.Sprintf(`DROP DATABASE "%s"`, name)
+	default:
+		return fmt.Sprintf("DROP DATABASE `%s`", name)
+	}
+}
+
+// ScratchDB returns an empty database with the driver of the database
+// options. SQLite uses an in-memory database. For MySQL and Postgres the
+// database name is created on the scratch server, or the server from
+// options if empty, and dropped by cleanup.
+func ScratchDB(ctx context.Context, options *db.Options, server, name string) (*sqlx.DB, func(), error) {
+	driver, _ := db.ParseDSN(options.Credentials.DSN)
+
+	scratchOptions := *options
+	if driver == "sqlite" {
+		scratchOptions.Credentials = db.NewCredentials("sqlite://:memory:")
+		handle, err := db.ConnectWithRetry(ctx, &scratchOptions)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error creating scratch database")
+		}
+		return handle, func() { handle.Close() }, nil
+	}
+
+	if server != "" {
+		if serverDriver, _ := db.ParseDSN(server); serverDriver != driver {
+			return nil, nil, errors.Errorf("scratch server driver %s doesn't match database driver %s", serverDriver, driver)
+		}
+		scratchOptions.Credentials = db.NewCredentials(server)
+	}
+
+	serverHandle, err := db.ConnectWithRetry(ctx, &scratchOptions)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error connecting to scratch server")
+	}
+
+	if _, err := serverHandle.ExecContext(ctx, CreateDatabaseQuery(driver, name)); err != nil {
+		serverHandle.Close()
+		return nil, nil, errors.Wrap(err, "error creating scratch database")
+	}
+	drop := func() {
+		if _, err := serverHandle.ExecContext(context.Background(), DropDatabaseQuery(driver, name)); err != nil {
+			log.Printf("error dropping scratch database %s: %s", name, err)
+		}
+		serverHandle.Close()
+	}
+
+	scratchOptions.Credentials, err = scratchOptions.Credentials.WithDatabase(name)
+	if err != nil {
+		drop()
+		return nil, nil, err
+	}
+
+	handle, err := db.ConnectWithRetry(ctx, &scratchOptions)
+	if err != nil {
+		drop()
+		return nil, nil, errors.Wrap(err, "error connecting to scratch database")
+	}
+
+	return handle, func() {
+		handle.Close()
+		drop()
+	}, nil
+}
+
+var nonIdentifier = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// ScratchName returns a unique scratch database name for a command and project.
+func ScratchName(command, project string) string {
+	project = nonIdentifier.ReplaceAllString(strings.ToLower(project), "_")
+	return fmt.Sprintf("mig_%s_%s_%d", command, project, time.Now().Unix())
+}
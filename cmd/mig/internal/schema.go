@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"slices"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-bridget/mig/db"
+	"github.com/go-bridget/mig/db/introspect"
+	"github.com/go-bridget/mig/migrate"
+	"github.com/go-bridget/mig/model"
+)
+
+// ReadSchema reads tables from a YAML or JSON file, as written
+// by `mig docs --yaml` or `mig docs --json`.
+func ReadSchema(filename string) ([]*model.Table, error) {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := []*model.Table{}
+	if err := yaml.Unmarshal(contents, &tables); err != nil {
+		return nil, errors.Wrapf(err, "error reading schema %s", filename)
+	}
+	return SchemaTables(tables), nil
+}
+
+// DescribeSchema returns the tables of a database with columns and indexes.
+func DescribeSchema(ctx context.Context, config *db.Options) ([]*model.Table, string, error) {
+	handle, err := db.ConnectWithRetry(ctx, config)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "error connecting to database")
+	}
+	defer handle.Close()
+
+	return Describe(ctx, handle)
+}
+
+// Describe returns the tables of a connected database with columns and indexes.
+func Describe(ctx context.Context, handle *sqlx.DB) ([]*model.Table, string, error) {
+	desc, err := introspect.NewDescriber(handle)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tables, err := introspect.ListTablesWithColumns(ctx, handle, desc)
+	if err != nil {
+		return nil, "", err
+	}
+	return SchemaTables(tables), handle.DriverName(), nil
+}
+
+// SchemaTables removes the migration bookkeeping tables, and tables
+// ignored with an `ignore` comment, from tables.
+func SchemaTables(tables []*model.Table) []*model.Table {
+	return slices.DeleteFunc(tables, func(table *model.Table) bool {
+		return migrate.IsBookkeeping(table.Name) || table.Ignore()
+	})
+}
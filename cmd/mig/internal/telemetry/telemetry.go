@@ -0,0 +1,187 @@
+// Package telemetry configures exporting of migration traces and metrics.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	flag "github.com/spf13/pflag"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Options include trace and metric export options.
+type Options struct {
+	// Endpoint is the OTLP/HTTP collector URL traces are sent to,
+	// e.g. `http://localhost:4318`.
+	Endpoint string
+
+	// TraceFile writes spans as JSON lines to a file.
+	TraceFile string
+
+	// MetricsFile writes metrics in the Prometheus text format to a
+	// file, e.g. for the node_exporter textfile collector.
+	MetricsFile string
+
+	// MetricsInterval is the interval the metrics file is written at.
+	MetricsInterval time.Duration
+
+	// MetricsListen serves metrics in the Prometheus text format on
+	// `/metrics` at the address, to be scraped by a local collector.
+	MetricsListen string
+
+	// Statements adds the executed statements to the spans.
+	Statements bool
+}
+
+// NewOptions creates a new Options instance with default values.
+func NewOptions() *Options {
+	return &Options{
+		Endpoint:        os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		MetricsInterval: 15 * time.Second,
+	}
+}
+
+// Bind registers telemetry flags on the given FlagSet.
+func (options *Options) Bind(fs *flag.FlagSet) {
+	fs.StringVar(&options.Endpoint, "otel-endpoint", options.Endpoint, "OTLP/HTTP collector URL for traces (e.g. http://localhost:4318)")
+	fs.StringVar(&options.TraceFile, "trace-file", options.TraceFile, "Write traces as JSON to a file")
+	fs.StringVar(&options.MetricsFile, "metrics-file", options.MetricsFile, "Write metrics in Prometheus text format to a file")
+	fs.DurationVar(&options.MetricsInterval, "metrics-interval", options.MetricsInterval, "Interval for writing the metrics file")
+	fs.StringVar(&options.MetricsListen, "metrics-listen", options.MetricsListen, "Serve metrics in Prometheus text format on /metrics (e.g. localhost:9464)")
+	fs.BoolVar(&options.Statements, "trace-statements", options.Statements, "Add the SQL statements to the spans (they may contain data)")
+}
+
+// Start configures the global trace and meter providers. The returned
+// function flushes the traces, writes the final metrics, and stops the
+// exporters. If no export is configured, Start does nothing.
+func Start(ctx context.Context, options *Options) (func(context.Context) error, error) {
+	var shutdown []func(context.Context) error
+	stop := func(ctx context.Context) error {
+		var errs []error
+		for i := len(shutdown) - 1; i >= 0; i-- {
+			errs = append(errs, shutdown[i](ctx))
+		}
+		return errors.Join(errs...)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", "mig")))
+	if err != nil {
+		return nil, err
+	}
+
+	// Traces
+	var spanExporters []sdktrace.SpanExporter
+	if options.Endpoint != "" {
+		exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(tracesURL(options.Endpoint)))
+		if err != nil {
+			return nil, fmt.Errorf("error creating OTLP trace exporter: %w", err)
+		}
+		spanExporters = append(spanExporters, exporter)
+	}
+	if options.TraceFile != "" {
+		f, err := os.Create(options.TraceFile)
+		if err != nil {
+			return nil, fmt.Errorf("error creating trace file: %w", err)
+		}
+		shutdown = append(shutdown, func(context.Context) error {
+			return f.Close()
+		})
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			return nil, errors.Join(err, stop(ctx))
+		}
+		spanExporters = append(spanExporters, exporter)
+	}
+	if len(spanExporters) > 0 {
+		providerOptions := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+		for _, exporter := range spanExporters {
+			providerOptions = append(providerOptions, sdktrace.WithBatcher(exporter))
+		}
+		provider := sdktrace.NewTracerProvider(providerOptions...)
+		otel.SetTracerProvider(provider)
+		shutdown = append(shutdown, provider.Shutdown)
+	}
+
+	// Metrics
+	if options.MetricsFile == "" && options.MetricsListen == "" {
+		return stop, nil
+	}
+
+	registry := prometheus.NewRegistry()
+	reader, err := otelprometheus.New(otelprometheus.WithRegisterer(registry), otelprometheus.WithoutScopeInfo())
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("error creating prometheus exporter: %w", err), stop(ctx))
+	}
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithResource(res), sdkmetric.WithReader(reader))
+	otel.SetMeterProvider(provider)
+
+	if options.MetricsListen != "" {
+		listener, err := net.Listen("tcp", options.MetricsListen)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("error listening for metrics: %w", err), stop(ctx))
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		server := &http.Server{Handler: mux}
+		go server.Serve(listener)
+
+		shutdown = append(shutdown, server.Shutdown)
+	}
+
+	if options.MetricsFile != "" {
+		done := make(chan struct{})
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+
+			ticker := time.NewTicker(max(options.MetricsInterval, time.Second))
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					if err := prometheus.WriteToTextfile(options.MetricsFile, registry); err != nil {
+						log.Println("error writing metrics:", err)
+					}
+				}
+			}
+		}()
+
+		shutdown = append(shutdown, func(ctx context.Context) error {
+			close(done)
+			<-stopped
+			return prometheus.WriteToTextfile(options.MetricsFile, registry)
+		})
+	}
+
+	// Shut down the meter provider last, after the final metrics are written.
+	shutdown = append([]func(context.Context) error{provider.Shutdown}, shutdown...)
+	return stop, nil
+}
+
+// tracesURL returns the OTLP/HTTP traces URL for a collector endpoint.
+func tracesURL(endpoint string) string {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	return strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/titpetric/cli"
 
+	"github.com/go-bridget/mig/cmd/mig/internal/configfile"
 	"github.com/go-bridget/mig/db"
 	"github.com/go-bridget/mig/db/introspect"
 )
@@ -17,7 +18,8 @@ const Name = "Lint database schema"
 
 // Options contains lint configuration.
 type Options struct {
-	db *db.Options
+	db   *db.Options
+	file *configfile.Options
 
 	skipComments bool
 	skipPlural   bool
@@ -33,11 +35,16 @@ func New() *cli.Command {
 		Bind: func(fs *cli.FlagSet) {
 			config.db = db.NewOptions()
 			config.db.Bind(fs)
+			config.file = configfile.Bind(fs)
 
 			fs.BoolVar(&config.skipComments, "skip-comments", false, "Skip validating table/column comments")
 			fs.BoolVar(&config.skipPlural, "skip-plural", false, "Skip validating table name for singular form")
 		},
 		Run: func(ctx context.Context, args []string) error {
+			if err := config.file.Apply("lint", ""); err != nil {
+				return err
+			}
+
 			handle, err := db.ConnectWithRetry(ctx, config.db)
 			if err != nil {
 				return err
@@ -4,6 +4,8 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+
+	"github.com/go-bridget/mig/migrate"
 )
 
 var (
@@ -14,8 +16,8 @@ var (
 
 func isTableNameValid(name string, options Options) error {
 	name = strings.ToLower(name)
-	// ignore migrations table
-	if strings.ToLower(name) == "migrations" {
+	// ignore migration bookkeeping tables
+	if migrate.IsBookkeeping(name) {
 		return nil
 	}
 	// check for plural suffix
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 
@@ -11,11 +12,18 @@ import (
 
 	"github.com/titpetric/cli"
 
+	"github.com/go-bridget/mig/cmd/mig/check"
 	"github.com/go-bridget/mig/cmd/mig/create"
+	"github.com/go-bridget/mig/cmd/mig/diff"
 	"github.com/go-bridget/mig/cmd/mig/docs"
+	"github.com/go-bridget/mig/cmd/mig/drift"
 	"github.com/go-bridget/mig/cmd/mig/gen"
 	"github.com/go-bridget/mig/cmd/mig/lint"
 	"github.com/go-bridget/mig/cmd/mig/migrate"
+	"github.com/go-bridget/mig/cmd/mig/plan"
+	"github.com/go-bridget/mig/cmd/mig/squash"
+	"github.com/go-bridget/mig/cmd/mig/status"
+	"github.com/go-bridget/mig/cmd/mig/verify"
 )
 
 // mig build info
@@ -27,6 +35,12 @@ var (
 func main() {
 	if err := run(); err != nil {
 		fmt.Fprintf(os.Stderr, "An error occurred: %s\n", err)
+
+		// commands may exit with a specific code, e.g. check
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
 		os.Exit(1)
 	}
 }
@@ -36,6 +50,13 @@ func run() error {
 
 	app.AddCommand("create", create.Name, create.New)
 	app.AddCommand("migrate", migrate.Name, migrate.New)
+	app.AddCommand("status", status.Name, status.New)
+	app.AddCommand("check", check.Name, check.New)
+	app.AddCommand("verify", verify.Name, verify.New)
+	app.AddCommand("plan", plan.Name, plan.New)
+	app.AddCommand("diff", diff.Name, diff.New)
+	app.AddCommand("drift", drift.Name, drift.New)
+	app.AddCommand("squash", squash.Name, squash.New)
 	app.AddCommand("docs", docs.Name, docs.New)
 	app.AddCommand("lint", lint.Name, lint.New)
 	app.AddCommand("gen", gen.Name, gen.New)
@@ -3,11 +3,18 @@ package migrate
 import (
 	"context"
 	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 
 	"github.com/titpetric/cli"
 
+	"github.com/go-bridget/mig/cmd/mig/internal/configfile"
+	"github.com/go-bridget/mig/cmd/mig/internal/telemetry"
 	"github.com/go-bridget/mig/db"
 	"github.com/go-bridget/mig/migrate"
 )
@@ -18,38 +25,273 @@ const Name = "Apply SQL migrations to database"
 // New creates a new migrate command.
 func New() *cli.Command {
 	var config struct {
-		db      *db.Options
-		migrate *migrate.Options
+		targets   *db.Targets
+		migrate   *migrate.Options
+		telemetry *telemetry.Options
+		notify    *migrate.NotifyOptions
+		all       bool
+		report    string
+		threshold time.Duration
+		file      *configfile.Options
 	}
 
 	return &cli.Command{
 		Name:  "migrate",
 		Title: Name,
 		Bind: func(fs *cli.FlagSet) {
-			config.db = db.NewOptions()
-			config.db.Bind(fs)
+			config.targets = db.NewTargets()
+			config.targets.Bind(fs)
 			config.migrate = migrate.NewOptions()
 			config.migrate.Bind(fs)
+			config.file = configfile.Bind(fs)
+			config.telemetry = telemetry.NewOptions()
+			config.telemetry.Bind(fs)
+			config.notify = migrate.NewNotifyOptions()
+			config.notify.Bind(fs)
+			fs.BoolVar(&config.all, "all", config.all, "Migrate each project in a subdirectory of --path, in dependency order")
+			fs.StringVar(&config.report, "report", config.report, "Write statement timings to a report file (.json or .md)")
+			fs.DurationVar(&config.threshold, "slow-threshold", time.Second, "Flag statements slower than this in the report (0 disables)")
 		},
-		Run: func(ctx context.Context, args []string) error {
+		Run: func(ctx context.Context, args []string) (err error) {
 			if len(args) > 0 {
 				config.migrate.Project = args[0]
 			}
 
-			if config.migrate.Project == "" {
-				return errors.New("Specify project name as first argument to migrate")
+			if err := config.file.Apply("migrate", config.migrate.Project); err != nil {
+				return err
 			}
 
-			if err := migrate.Load(config.migrate); err != nil {
-				return fmt.Errorf("error loading migrations: %w", err)
+			if err := checkReport(config.report); err != nil {
+				return err
 			}
 
-			switch {
-			case config.migrate.Apply:
-				return migrate.Run(ctx, config.db, config.migrate)
-			default:
-				return migrate.Print(config.migrate)
+			projects, err := load(config.migrate, config.all)
+			if err != nil {
+				return err
 			}
+
+			if !config.migrate.Apply && !config.migrate.CheckSafety {
+				for _, project := range projects {
+					options := *config.migrate
+					options.Project = project
+					if err := migrate.Print(&options); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
+			ctx, stop, release := gracefulStop(ctx)
+			defer release()
+			config.migrate.Stop = stop
+			config.migrate.TraceStatements = config.telemetry.Statements
+
+			shutdown, err := telemetry.Start(ctx, config.telemetry)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if serr := shutdown(context.Background()); serr != nil {
+					log.Println("error exporting telemetry:", serr)
+				}
+			}()
+
+			// Runs checking safety without --apply don't change the
+			// databases, and aren't notified.
+			notifyOptions := config.notify
+			if !config.migrate.Apply {
+				notifyOptions = migrate.NewNotifyOptions()
+			}
+
+			start := time.Now()
+			targets, err := config.targets.List()
+			if err == nil {
+				targets, err = config.targets.ExpandSchemas(ctx, targets)
+			}
+			if err != nil {
+				notifyFailed(notifyOptions, start, err)
+				return err
+			}
+
+			timings := map[string]*migrate.Timings{}
+			for _, target := range targets {
+				timings[target.Name] = &migrate.Timings{}
+			}
+
+			if len(targets) == 1 {
+				config.migrate.Timings = timings[targets[0].Name]
+				config.migrate.Schema = targets[0].Schema
+				reports, err := run(ctx, targets[0].Options, config.migrate, projects)
+				results := []*db.TargetResult{{Target: targets[0], Duration: time.Since(start), Err: err}}
+				reportsByTarget := map[*db.Target][]*projectReport{targets[0]: reports}
+				notify(notifyOptions, start, results, reportsByTarget)
+				if rerr := writeReport(config.report, timings, config.threshold); rerr != nil && err == nil {
+					return rerr
+				}
+
+				if len(projects) > 1 {
+					return summary(results, reportsByTarget)
+				}
+				if errors.Is(err, migrate.ErrStopped) {
+					log.Println("migrations stopped, run migrate again to resume")
+					return nil
+				}
+				return err
+			}
+
+			var mu sync.Mutex
+			reports := map[*db.Target][]*projectReport{}
+			results := config.targets.Run(ctx, targets, func(ctx context.Context, target *db.Target) error {
+				select {
+				case <-stop:
+					return migrate.ErrStopped
+				default:
+				}
+
+				options := *config.migrate
+				options.Logger = log.New(os.Stderr, target.Name+": ", log.LstdFlags)
+				options.Timings = timings[target.Name]
+				options.Schema = target.Schema
+				report, err := run(ctx, target.Options, &options, projects)
+
+				mu.Lock()
+				reports[target] = report
+				mu.Unlock()
+				return err
+			})
+			notify(notifyOptions, start, results, reports)
+			err = summary(results, reports)
+			if rerr := writeReport(config.report, timings, config.threshold); rerr != nil && err == nil {
+				return rerr
+			}
+			return err
 		},
 	}
 }
+
+// load reads the migrations and returns the projects to migrate. With all,
+// each subdirectory of the path is a project, ordered by dependencies.
+func load(options *migrate.Options, all bool) ([]string, error) {
+	if all {
+		projects, err := migrate.LoadAll(options)
+		if err != nil {
+			return nil, fmt.Errorf("error loading migrations: %w", err)
+		}
+		return projects, nil
+	}
+
+	if options.Project == "" {
+		return nil, errors.New("Specify project name as first argument to migrate, or use --all")
+	}
+	if err := migrate.Load(options); err != nil {
+		return nil, fmt.Errorf("error loading migrations: %w", err)
+	}
+	return []string{options.Project}, nil
+}
+
+// run checks and applies migrations for each project to a database.
+// Projects are migrated in order, and the first failure stops the run;
+// the remaining projects are reported as skipped.
+func run(ctx context.Context, dbOptions *db.Options, options *migrate.Options, projects []string) ([]*projectReport, error) {
+	handle, err := db.ConnectWithRetry(ctx, dbOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "error connecting to database")
+	}
+	defer handle.Close()
+
+	reports := make([]*projectReport, len(projects))
+	for idx, project := range projects {
+		reports[idx] = &projectReport{Project: project, Skipped: true}
+	}
+
+	for _, report := range reports {
+		projectOptions := *options
+		projectOptions.Project = report.Project
+
+		start := time.Now()
+		report.Skipped = false
+		report.Files, report.Err = migrateProject(ctx, handle, &projectOptions)
+		report.Duration = time.Since(start)
+		if report.Err != nil {
+			return reports, report.Err
+		}
+	}
+	return reports, nil
+}
+
+// migrateProject checks and applies migrations for a project. It returns
+// the migration files applied.
+func migrateProject(ctx context.Context, handle *sqlx.DB, options *migrate.Options) ([]string, error) {
+	if options.CheckSafety {
+		if err := checkSafety(ctx, handle, options); err != nil {
+			return nil, err
+		}
+	}
+	if !options.Apply {
+		return nil, nil
+	}
+
+	fs, err := migrate.Loaded(options.Project)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := migrate.Pending(ctx, handle, fs, options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = migrate.RunWithDB(ctx, handle, options)
+
+	after, perr := migrate.Pending(ctx, handle, fs, options)
+	if perr != nil {
+		if err == nil {
+			err = perr
+		}
+		return nil, err
+	}
+
+	pending := map[string]bool{}
+	for _, file := range after {
+		pending[file.Filename] = true
+	}
+	applied := []string{}
+	for _, file := range before {
+		if file.State != migrate.StateAhead && !pending[file.Filename] {
+			applied = append(applied, file.Filename)
+		}
+	}
+	return applied, err
+}
+
+// checkSafety prints issues found in pending statements. It returns
+// an error if any of the issues have error severity.
+func checkSafety(ctx context.Context, handle *sqlx.DB, options *migrate.Options) error {
+	fs, err := migrate.Loaded(options.Project)
+	if err != nil {
+		return err
+	}
+
+	issues, err := migrate.CheckSafety(ctx, handle, fs, options)
+	if err != nil {
+		return err
+	}
+
+	var errorCount int
+	for _, issue := range issues {
+		fmt.Println(issue)
+		if options.Verbose {
+			fmt.Println()
+			fmt.Println(issue.Query)
+			fmt.Println()
+		}
+		if issue.Severity == migrate.SeverityError {
+			errorCount++
+		}
+	}
+
+	if errorCount > 0 {
+		return errors.Errorf("safety check failed: %d errors, %d warnings (allow with `-- mig:allow <rule>`)", errorCount, len(issues)-errorCount)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	flag "github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/go-bridget/mig/migrate"
+)
+
+// runCommand runs the migrate command with args.
+func runCommand(t *testing.T, args ...string) error {
+	t.Helper()
+
+	command := New()
+	fs := flag.NewFlagSet(command.Name, flag.ContinueOnError)
+	command.Bind(fs)
+	require.NoError(t, fs.Parse(args))
+	return command.Run(context.Background(), fs.Args())
+}
+
+func TestMigrateNotify(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "1-users.up.sql"), []byte("CREATE TABLE users (id integer);"), 0644))
+
+	output := filepath.Join(dir, "summary.json")
+	dsn := "sqlite://" + filepath.Join(dir, "app.db")
+	args := []string{"app", "--path", dir, "--notify-command", "cat > " + output}
+
+	summary := func() *migrate.Summary {
+		t.Helper()
+		contents, err := os.ReadFile(output)
+		require.NoError(t, err)
+		require.NoError(t, os.Remove(output))
+
+		result := &migrate.Summary{}
+		require.NoError(t, json.Unmarshal(contents, result))
+		return result
+	}
+
+	// Safety checks without --apply aren't notified
+	require.NoError(t, runCommand(t, append(args, "--db-dsn", dsn, "--check-safety")...))
+	require.NoFileExists(t, output)
+
+	require.NoError(t, runCommand(t, append(args, "--db-dsn", dsn, "--apply")...))
+	result := summary()
+	require.Equal(t, migrate.SummaryOK, result.Status)
+	require.Len(t, result.Databases, 1)
+	require.Equal(t, []string{"1-users.up.sql"}, result.Databases[0].Projects[0].Files)
+
+	// Errors listing the databases send a failed summary
+	err := runCommand(t, append(args, "--db-dsn-file", filepath.Join(dir, "missing.txt"), "--apply")...)
+	require.Error(t, err)
+	result = summary()
+	require.Equal(t, migrate.SummaryFailed, result.Status)
+	require.Equal(t, err.Error(), result.Error)
+	require.Empty(t, result.Databases)
+}
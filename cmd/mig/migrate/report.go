@@ -0,0 +1,233 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/go-bridget/mig/db"
+	"github.com/go-bridget/mig/migrate"
+)
+
+// projectReport holds the result of migrating a project on a database.
+type projectReport struct {
+	Project string
+
+	// Skipped is true if the project wasn't migrated after an error.
+	Skipped bool
+
+	// Files lists the migration files applied.
+	Files []string
+
+	Duration time.Duration
+	Err      error
+}
+
+// summary prints the results for each database and project. It returns
+// an error if migrations failed for any of the databases.
+func summary(results []*db.TargetResult, reports map[*db.Target][]*projectReport) error {
+	var failed, skipped, stopped int
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DATABASE\tPROJECT\tSTATE\tFILES\tDURATION\tERROR")
+	for _, result := range results {
+		switch {
+		case result.Skipped:
+			skipped++
+		case errors.Is(result.Err, migrate.ErrStopped):
+			stopped++
+		case result.Err != nil:
+			failed++
+		}
+
+		projects := reports[result.Target]
+		if len(projects) == 0 {
+			// The database was skipped, or the connection failed.
+			state, duration, message := resultState(result.Skipped, result.Duration, result.Err)
+			fmt.Fprintf(w, "%s\t-\t%s\t-\t%s\t%s\n", result.Target.Name, state, duration, message)
+			continue
+		}
+
+		for _, project := range projects {
+			state, duration, message := resultState(project.Skipped, project.Duration, project.Err)
+			files := "-"
+			if !project.Skipped {
+				files = strconv.Itoa(len(project.Files))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", result.Target.Name, project.Project, state, files, duration, message)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return errors.Errorf("migrations failed for %d of %d databases (%d skipped, %d stopped)", failed, len(results), skipped, stopped)
+	}
+	if stopped > 0 {
+		log.Printf("migrations stopped for %d of %d databases, run migrate again to resume", stopped, len(results))
+	}
+	return nil
+}
+
+// resultState returns the state, duration and error columns for a result.
+func resultState(skipped bool, duration time.Duration, err error) (string, string, string) {
+	state := strings.ToUpper(summaryState(skipped, err))
+	if skipped {
+		return state, "-", ""
+	}
+	return state, duration.Round(time.Millisecond).String(), errorString(err)
+}
+
+// summaryState returns the migrate.Summary* state for a result.
+func summaryState(skipped bool, err error) string {
+	switch {
+	case skipped:
+		return migrate.SummarySkipped
+	case errors.Is(err, migrate.ErrStopped):
+		return migrate.SummaryStopped
+	case err != nil:
+		return migrate.SummaryFailed
+	}
+	return migrate.SummaryOK
+}
+
+// notify sends the run summary to the configured webhooks and commands.
+// Errors are logged, and don't change the result of the run.
+func notify(options *migrate.NotifyOptions, start time.Time, results []*db.TargetResult, reports map[*db.Target][]*projectReport) {
+	if !options.Enabled() {
+		return
+	}
+	send(options, newSummary(start, results, reports))
+}
+
+// notifyFailed sends a failed run summary for errors before the
+// databases are migrated, e.g. when listing tenant schemas fails.
+func notifyFailed(options *migrate.NotifyOptions, start time.Time, err error) {
+	if !options.Enabled() {
+		return
+	}
+	send(options, &migrate.Summary{
+		Status:     migrate.SummaryFailed,
+		Started:    start,
+		DurationMS: time.Since(start).Milliseconds(),
+		Error:      err.Error(),
+		Databases:  []*migrate.DatabaseSummary{},
+	})
+}
+
+// send sends the summary, and logs errors.
+func send(options *migrate.NotifyOptions, summary *migrate.Summary) {
+	// Notifications are sent after a graceful stop too, so they don't use the run context.
+	if err := migrate.Notify(context.Background(), options, summary); err != nil {
+		log.Println("error sending notifications:", err)
+	}
+}
+
+// newSummary returns the run summary for notifications.
+func newSummary(start time.Time, results []*db.TargetResult, reports map[*db.Target][]*projectReport) *migrate.Summary {
+	result := &migrate.Summary{
+		Status:     migrate.SummaryOK,
+		Started:    start,
+		DurationMS: time.Since(start).Milliseconds(),
+		Databases:  []*migrate.DatabaseSummary{},
+	}
+
+	for _, target := range results {
+		database := &migrate.DatabaseSummary{
+			Database:   target.Target.Name,
+			Status:     summaryState(target.Skipped, target.Err),
+			DurationMS: target.Duration.Milliseconds(),
+			Error:      errorString(target.Err),
+			Projects:   []*migrate.ProjectSummary{},
+		}
+		for _, project := range reports[target.Target] {
+			files := project.Files
+			if files == nil {
+				files = []string{}
+			}
+			database.Projects = append(database.Projects, &migrate.ProjectSummary{
+				Project:    project.Project,
+				Status:     summaryState(project.Skipped, project.Err),
+				Files:      files,
+				DurationMS: project.Duration.Milliseconds(),
+				Error:      errorString(project.Err),
+			})
+		}
+		result.Databases = append(result.Databases, database)
+
+		switch database.Status {
+		case migrate.SummaryFailed:
+			result.Status = migrate.SummaryFailed
+		case migrate.SummaryStopped:
+			if result.Status == migrate.SummaryOK {
+				result.Status = migrate.SummaryStopped
+			}
+		}
+	}
+	return result
+}
+
+// errorString returns the error message, or an empty string for nil
+// errors and graceful stops.
+func errorString(err error) string {
+	if err == nil || errors.Is(err, migrate.ErrStopped) {
+		return ""
+	}
+	return err.Error()
+}
+
+// slowestStatements is the number of slowest statements listed in the report.
+const slowestStatements = 10
+
+// checkReport validates the report filename before migrations run.
+// An empty filename doesn't write a report.
+func checkReport(filename string) error {
+	if filename == "" {
+		return nil
+	}
+	switch filepath.Ext(filename) {
+	case ".json", ".md":
+		return nil
+	}
+	return errors.Errorf("unsupported report format: %s, use a .json or .md file", filename)
+}
+
+// writeReport writes the statement timings to the report file,
+// formatted by the file extension. It's a no-op without a filename.
+func writeReport(filename string, timings map[string]*migrate.Timings, threshold time.Duration) error {
+	if filename == "" {
+		return nil
+	}
+
+	report := migrate.NewReport(timings, threshold, slowestStatements)
+
+	var buf bytes.Buffer
+	var err error
+	if filepath.Ext(filename) == ".md" {
+		err = report.WriteMarkdown(&buf)
+	} else {
+		err = report.WriteJSON(&buf)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		return errors.Wrap(err, "error writing report")
+	}
+	if len(report.Slow) > 0 {
+		log.Printf("%d statements exceeded the %s threshold, see %s", len(report.Slow), threshold, filename)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-bridget/mig/db"
+	"github.com/go-bridget/mig/migrate"
+)
+
+func TestCheckReport(t *testing.T) {
+	require.NoError(t, checkReport(""))
+	require.NoError(t, checkReport("report.json"))
+	require.NoError(t, checkReport("out/report.md"))
+
+	require.ErrorContains(t, checkReport("timings"), "unsupported report format: timings")
+	require.ErrorContains(t, checkReport("report.txt"), "unsupported report format: report.txt")
+}
+
+// captureStdout returns what fn prints to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	fn()
+	w.Close()
+
+	output, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(output)
+}
+
+func TestSummary(t *testing.T) {
+	primary := &db.Target{Name: "primary"}
+	replica := &db.Target{Name: "replica"}
+	offline := &db.Target{Name: "offline"}
+	failed := errors.New("no such table: accounts")
+
+	results := []*db.TargetResult{
+		{Target: primary, Duration: 20 * time.Millisecond},
+		{Target: replica, Duration: 5 * time.Millisecond, Err: failed},
+		{Target: offline, Err: errors.New("error connecting to database")},
+	}
+	reports := map[*db.Target][]*projectReport{
+		primary: {
+			{Project: "accounts", Files: []string{"1-accounts.up.sql", "2-profiles.up.sql"}, Duration: 14 * time.Millisecond},
+			{Project: "billing", Files: []string{}, Duration: 3 * time.Millisecond},
+		},
+		replica: {
+			{Project: "accounts", Duration: 5 * time.Millisecond, Err: failed},
+			{Project: "billing", Skipped: true},
+		},
+	}
+
+	var err error
+	output := captureStdout(t, func() {
+		err = summary(results, reports)
+	})
+	require.EqualError(t, err, "migrations failed for 2 of 3 databases (0 skipped, 0 stopped)")
+	require.Equal(t, `
+DATABASE  PROJECT   STATE    FILES  DURATION  ERROR
+primary   accounts  OK       2      14ms      
+primary   billing   OK       0      3ms       
+replica   accounts  FAILED   0      5ms       no such table: accounts
+replica   billing   SKIPPED  -      -         
+offline   -         FAILED   -      0s        error connecting to database
+`, output)
+
+	result := newSummary(time.Now(), results, reports)
+	require.Equal(t, migrate.SummaryFailed, result.Status)
+	require.Len(t, result.Databases, 3)
+	require.Equal(t, migrate.SummaryOK, result.Databases[0].Status)
+	require.Equal(t, []string{"1-accounts.up.sql", "2-profiles.up.sql"}, result.Databases[0].Projects[0].Files)
+	require.Equal(t, migrate.SummaryFailed, result.Databases[1].Projects[0].Status)
+	require.Equal(t, "no such table: accounts", result.Databases[1].Projects[0].Error)
+	require.Equal(t, migrate.SummarySkipped, result.Databases[1].Projects[1].Status)
+	require.Equal(t, []string{}, result.Databases[1].Projects[1].Files)
+	require.Empty(t, result.Databases[2].Projects)
+}
+
+func TestMigrateAll(t *testing.T) {
+	dir := t.TempDir()
+	write := func(filename, contents string) {
+		filename = filepath.Join(dir, filename)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+		require.NoError(t, os.WriteFile(filename, []byte(contents), 0644))
+	}
+	write("accounts/1-accounts.up.sql", "CREATE TABLE accounts (id integer);")
+	write("billing/1-invoices.up.sql", "CREATE TABLE invoices (account_id integer);")
+	write("billing/"+migrate.ManifestFile, "requires:\n  - project: accounts\n")
+
+	primary := "sqlite://" + filepath.Join(dir, "primary.db")
+	replica := "sqlite://" + filepath.Join(dir, "replica.db")
+
+	var err error
+	output := captureStdout(t, func() {
+		err = runCommand(t, "--all", "--path", dir, "--apply", "--db-dsn", primary, "--db-dsn", replica)
+	})
+	require.NoError(t, err)
+
+	// The report lists each project of each database
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	require.Len(t, lines, 5)
+	require.Equal(t, []string{"DATABASE", "PROJECT", "STATE", "FILES", "DURATION", "ERROR"}, strings.Fields(lines[0]))
+	for idx, want := range [][]string{
+		{primary, "accounts", "OK", "1"},
+		{primary, "billing", "OK", "1"},
+		{replica, "accounts", "OK", "1"},
+		{replica, "billing", "OK", "1"},
+	} {
+		require.Equal(t, want, strings.Fields(lines[idx+1])[:4])
+	}
+}
@@ -0,0 +1,48 @@
+package migrate
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// gracefulStop handles interrupt and termination signals. The first signal
+// closes the returned stop channel, so migrations stop after the statement
+// being executed. The second signal cancels the returned context, aborting
+// the statement. The context isn't cancelled with ctx, so the statement
+// isn't aborted on the first signal. Call the returned function to stop
+// handling signals.
+func gracefulStop(ctx context.Context) (context.Context, <-chan struct{}, func()) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		log.Println("stopping after the current statement, repeat the signal to abort")
+		close(stop)
+
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		log.Println("aborting the current statement")
+		cancel()
+	}()
+
+	return ctx, stop, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
@@ -0,0 +1,127 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/titpetric/cli"
+
+	"github.com/go-bridget/mig/cmd/mig/internal"
+	"github.com/go-bridget/mig/cmd/mig/internal/configfile"
+	"github.com/go-bridget/mig/db"
+	"github.com/go-bridget/mig/migrate"
+	"github.com/go-bridget/mig/model"
+)
+
+// Name is the command title.
+const Name = "Generate a migration from a desired schema"
+
+// New creates a new plan command.
+func New() *cli.Command {
+	var config struct {
+		db   *db.Options
+		file *configfile.Options
+
+		desired string
+		path    string
+		name    string
+		print   bool
+	}
+
+	return &cli.Command{
+		Name:  "plan",
+		Title: Name,
+		Bind: func(fs *cli.FlagSet) {
+			config.db = db.NewOptions()
+			config.db.Bind(fs)
+			config.file = configfile.Bind(fs)
+
+			fs.StringVar(&config.desired, "desired", "", "Desired schema as YAML or JSON (see docs --yaml)")
+			fs.StringVar(&config.path, "path", "schema", "Project path to write the migration to")
+			fs.StringVar(&config.name, "name", "plan", "Migration name, added to the timestamp in the filename")
+			fs.BoolVar(&config.print, "print", false, "Print the migration instead of writing a file")
+		},
+		Run: func(ctx context.Context, args []string) error {
+			var project string
+			if len(args) > 0 {
+				project = args[0]
+			}
+
+			if err := config.file.Apply("plan", project); err != nil {
+				return err
+			}
+
+			if config.desired == "" {
+				return errors.New("Specify the desired schema with --desired")
+			}
+
+			desired, err := internal.ReadSchema(config.desired)
+			if err != nil {
+				return err
+			}
+
+			current, driver, err := internal.DescribeSchema(ctx, config.db)
+			if err != nil {
+				return err
+			}
+
+			diff := model.Compare(current, desired)
+			if diff.Empty() {
+				fmt.Println("Schema is up to date")
+				return nil
+			}
+
+			stmts, err := migrate.Plan(diff, driver)
+			if err != nil {
+				return err
+			}
+
+			contents := render(config.desired, stmts)
+			defer warnReview(stmts)
+			if config.print {
+				fmt.Print(contents)
+				return nil
+			}
+
+			filename := filepath.Join(config.path, time.Now().Format("2006-01-02-150405")+"-"+config.name+".up.sql")
+			if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+				return err
+			}
+
+			fmt.Print(diff)
+			fmt.Println()
+			fmt.Println(filename)
+			return nil
+		},
+	}
+}
+
+// render returns the migration file contents for the statements.
+func render(source string, stmts []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "-- Generated by mig plan from %s, review before applying.\n\n", filepath.Base(source))
+	for _, stmt := range stmts {
+		sb.WriteString(stmt)
+		sb.WriteString(";\n\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// warnReview prints a warning for statements with a `-- review:` comment.
+func warnReview(stmts []string) {
+	var count int
+	for _, stmt := range stmts {
+		if strings.HasPrefix(stmt, "-- review:") {
+			count++
+		}
+	}
+	if count > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d statements may drop column attributes, see the -- review: comments\n", count)
+	}
+}
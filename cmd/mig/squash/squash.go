@@ -0,0 +1,132 @@
+package squash
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/titpetric/cli"
+
+	"github.com/go-bridget/mig/cmd/mig/internal/configfile"
+	"github.com/go-bridget/mig/migrate"
+)
+
+// Name is the command title.
+const Name = "Squash old migrations into a baseline replaying them"
+
+// New creates a new squash command.
+func New() *cli.Command {
+	var config struct {
+		migrate *migrate.Options
+		file    *configfile.Options
+
+		before  string
+		archive string
+		print   bool
+	}
+
+	return &cli.Command{
+		Name:  "squash",
+		Title: Name,
+		Bind: func(fs *cli.FlagSet) {
+			config.migrate = migrate.NewOptions()
+			config.migrate.Bind(fs)
+			config.file = configfile.Bind(fs)
+
+			fs.StringVar(&config.before, "before", "", "Squash the migrations before this file")
+			fs.StringVar(&config.archive, "archive", "archive", "Folder for the squashed files, relative to the project path")
+			fs.BoolVar(&config.print, "print", false, "Print the baseline instead of writing it and archiving the files, to review the replayed statements")
+		},
+		Run: func(ctx context.Context, args []string) error {
+			if len(args) > 0 {
+				config.migrate.Project = args[0]
+			}
+
+			if err := config.file.Apply("squash", config.migrate.Project); err != nil {
+				return err
+			}
+
+			if config.migrate.Project == "" {
+				return errors.New("Specify project name as first argument to squash")
+			}
+			if config.before == "" {
+				return errors.New("Specify the first migration to keep with --before")
+			}
+			if config.migrate.Filename != "" || config.migrate.GitRef != "" || migrate.IsArchive(config.migrate.Path) {
+				return errors.New("Squash works on a project directory, not a single --filename, an archive or --git-ref")
+			}
+
+			if err := migrate.Load(config.migrate); err != nil {
+				return fmt.Errorf("error loading migrations: %w", err)
+			}
+
+			fs, err := migrate.Loaded(config.migrate.Project)
+			if err != nil {
+				return err
+			}
+
+			baseline, err := migrate.Squash(fs, config.before)
+			if err != nil {
+				return err
+			}
+
+			if config.print {
+				fmt.Print(string(baseline.Contents))
+				return nil
+			}
+
+			return write(config.migrate.Path, config.archive, baseline, fs)
+		},
+	}
+}
+
+// write writes the baseline to the project path, and moves the
+// replaced files and their down migrations to the archive folder.
+func write(path, archive string, baseline *migrate.Baseline, fs migrate.FS) error {
+	if !filepath.IsAbs(archive) {
+		archive = filepath.Join(path, archive)
+	}
+
+	files := []string{}
+	for _, filename := range baseline.Replaces {
+		files = append(files, filename)
+		if down, ok := fs.Down(filename); ok {
+			files = append(files, down)
+		}
+	}
+	for _, filename := range files {
+		if _, err := os.Stat(filepath.Join(archive, filename)); err == nil {
+			return errors.Errorf("%s already exists in %s", filename, archive)
+		}
+	}
+
+	if err := os.MkdirAll(archive, 0755); err != nil {
+		return err
+	}
+
+	filename := filepath.Join(path, baseline.Filename)
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(baseline.Contents); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	for _, name := range files {
+		if err := os.Rename(filepath.Join(path, name), filepath.Join(archive, name)); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println(filename)
+	fmt.Printf("moved %d files to %s\n", len(files), archive)
+	return nil
+}
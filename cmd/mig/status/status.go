@@ -0,0 +1,152 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/titpetric/cli"
+
+	"github.com/go-bridget/mig/cmd/mig/internal/configfile"
+	"github.com/go-bridget/mig/db"
+	"github.com/go-bridget/mig/migrate"
+)
+
+// Name is the command title.
+const Name = "Show migration status for project"
+
+// New creates a new status command.
+func New() *cli.Command {
+	var config struct {
+		db      *db.Options
+		migrate *migrate.Options
+		file    *configfile.Options
+		history bool
+		limit   int
+	}
+
+	return &cli.Command{
+		Name:  "status",
+		Title: Name,
+		Bind: func(fs *cli.FlagSet) {
+			config.db = db.NewOptions()
+			config.db.Bind(fs)
+			config.migrate = migrate.NewOptions()
+			config.migrate.Bind(fs)
+			config.file = configfile.Bind(fs)
+			fs.BoolVar(&config.history, "history", config.history, "Show the applied statements with their execution time, most recent first")
+			fs.IntVar(&config.limit, "history-limit", 50, "Number of statements shown with --history (0 shows all)")
+		},
+		Run: func(ctx context.Context, args []string) error {
+			if len(args) > 0 {
+				config.migrate.Project = args[0]
+			}
+
+			if err := config.file.Apply("status", config.migrate.Project); err != nil {
+				return err
+			}
+
+			if config.migrate.Project == "" {
+				return errors.New("Specify project name as first argument to status")
+			}
+
+			if err := migrate.Load(config.migrate); err != nil {
+				return fmt.Errorf("error loading migrations: %w", err)
+			}
+
+			handle, err := db.ConnectWithRetry(ctx, config.db)
+			if err != nil {
+				return errors.Wrap(err, "error connecting to database")
+			}
+
+			if config.history {
+				return history(ctx, handle, config.migrate.Project, config.limit)
+			}
+
+			fs, err := migrate.Loaded(config.migrate.Project)
+			if err != nil {
+				return err
+			}
+
+			files, err := migrate.Status(ctx, handle, fs, config.migrate)
+			if err != nil {
+				return err
+			}
+
+			transactional := db.TransactionalDDL(handle.DriverName())
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "FILENAME\tSTATE\tSTATEMENTS")
+			notes := []string{}
+			for _, file := range files {
+				fmt.Fprintf(w, "%s\t%s\t%d/%d\n", file.Filename, strings.ToUpper(file.State), file.Applied, file.Statements)
+				if note := explain(file, transactional); note != "" {
+					notes = append(notes, file.Filename+": "+note)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			if len(notes) > 0 {
+				fmt.Println()
+				for _, note := range notes {
+					fmt.Println(note)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// history prints the applied statements for a project, most recent first.
+func history(ctx context.Context, handle *sqlx.DB, project string, limit int) error {
+	entries, err := migrate.History(ctx, handle, project, limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "APPLIED AT\tFILENAME\tSTATEMENT\tDURATION")
+	for _, entry := range entries {
+		appliedAt := entry.AppliedAt.Local().Format(time.DateTime)
+		duration := entry.Duration().Round(time.Microsecond)
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", appliedAt, entry.Filename, entry.StatementIndex+1, duration)
+	}
+	return w.Flush()
+}
+
+// explain describes partially applied and failed migration files.
+func explain(file *migrate.FileStatus, transactional bool) string {
+	next := file.Applied + 1
+
+	switch file.State {
+	case migrate.StatePartial:
+		note := fmt.Sprintf("run was interrupted, statements 1-%d of %d are applied.", file.Applied, file.Statements)
+		if !transactional {
+			note += fmt.Sprintf(" DDL commits implicitly on this database, so statement %d may have been applied without being recorded.", next)
+			note += " Verify the schema before running migrate, which resumes at statement " + fmt.Sprint(next) + "."
+		}
+		return note
+	case migrate.StateFailed:
+		if strings.HasPrefix(file.Error, "assertion failed") {
+			return file.Error + "."
+		}
+		note := fmt.Sprintf("statement %d of %d failed: %s.", next, file.Statements, file.Error)
+		if file.Applied > 0 {
+			note += fmt.Sprintf(" Statements 1-%d are applied, migrate resumes at statement %d.", file.Applied, next)
+		}
+		return note
+	case migrate.StatePending:
+		if file.Applied > 0 {
+			return fmt.Sprintf("statements %d-%d were appended after the file was applied.", next, file.Statements)
+		}
+	}
+	return ""
+}
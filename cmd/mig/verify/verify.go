@@ -0,0 +1,67 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/titpetric/cli"
+
+	"github.com/go-bridget/mig/cmd/mig/internal/configfile"
+	"github.com/go-bridget/mig/db"
+	"github.com/go-bridget/mig/migrate"
+)
+
+// Name is the command title.
+const Name = "Verify down migrations reverse up migrations"
+
+// New creates a new verify command.
+func New() *cli.Command {
+	var config struct {
+		db      *db.Options
+		migrate *migrate.Options
+		file    *configfile.Options
+	}
+
+	return &cli.Command{
+		Name:  "verify",
+		Title: Name,
+		Bind: func(fs *cli.FlagSet) {
+			config.db = db.NewOptions()
+			config.db.Bind(fs)
+			config.migrate = migrate.NewOptions()
+			config.migrate.Bind(fs)
+			config.file = configfile.Bind(fs)
+		},
+		Run: func(ctx context.Context, args []string) error {
+			if len(args) > 0 {
+				config.migrate.Project = args[0]
+			}
+
+			if err := config.file.Apply("verify", config.migrate.Project); err != nil {
+				return err
+			}
+
+			if config.migrate.Project == "" {
+				return errors.New("Specify project name as first argument to verify")
+			}
+
+			if err := migrate.Load(config.migrate); err != nil {
+				return fmt.Errorf("error loading migrations: %w", err)
+			}
+
+			handle, err := db.ConnectWithRetry(ctx, config.db)
+			if err != nil {
+				return errors.Wrap(err, "error connecting to database")
+			}
+
+			fs, err := migrate.Loaded(config.migrate.Project)
+			if err != nil {
+				return err
+			}
+
+			return migrate.Verify(ctx, handle, fs, config.migrate)
+		},
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"os"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
@@ -55,5 +56,10 @@ func ConnectWithOptions(ctx context.Context, options *Options) (*sqlx.DB, error)
 	}
 	db.SetMaxOpenConns(800)
 	db.SetMaxIdleConns(800)
+
+	// Each connection to an in-memory SQLite database opens a new database.
+	if driver == "sqlite" && strings.Contains(dsn, ":memory:") {
+		db.SetMaxOpenConns(1)
+	}
 	return db, nil
 }
@@ -7,10 +7,27 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/go-bridget/mig/db")
+
 // ConnectWithRetry uses retry options set in Options{}.
+// Failed connection attempts are recorded as events on a `mig.connect` span.
 func ConnectWithRetry(ctx context.Context, options *Options) (db *sqlx.DB, err error) {
+	driver, _ := options.Credentials.Open()
+	ctx, span := tracer.Start(ctx, "mig.connect", trace.WithAttributes(attribute.String("db.system", driver)))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	// by default, retry for 5 minutes, 5 seconds between retries
 	if options.Retries == 0 && options.ConnectTimeout.Seconds() == 0 {
 		options.ConnectTimeout = 5 * time.Minute
@@ -32,6 +49,10 @@ func ConnectWithRetry(ctx context.Context, options *Options) (db *sqlx.DB, err e
 			db, err = ConnectWithOptions(ctx, options)
 			if err != nil {
 				log.Printf("can't connect, err=%s, try=%d", err, try)
+				span.AddEvent("retry", trace.WithAttributes(
+					attribute.Int("mig.retry.attempt", try),
+					attribute.String("error", err.Error()),
+				))
 
 				if errors.Is(err, ErrEmptyDSN) {
 					break
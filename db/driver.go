@@ -0,0 +1,13 @@
+package db
+
+// TransactionalDDL returns true if the driver can roll back DDL statements.
+//
+// MySQL implicitly commits the active transaction before and after most DDL
+// statements, so a transaction gives no atomicity for schema changes there.
+func TransactionalDDL(driverName string) bool {
+	switch driverName {
+	case "mysql":
+		return false
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"regexp"
 	"strings"
 )
 
@@ -41,6 +42,9 @@ func (Credentials) parse(dsn string) (string, string) {
 		scheme := strings.Split(dsn, "://")
 		switch scheme[0] {
 		case "postgres", "postgresql":
+			if isKeywordDSN(scheme[1]) {
+				return "pgx", scheme[1]
+			}
 			return "pgx", "postgres://" + scheme[1]
 		case "mysql":
 			return "mysql", scheme[1]
@@ -49,6 +53,11 @@ func (Credentials) parse(dsn string) (string, string) {
 		}
 	}
 
+	// The key=value form of postgres DSNs (host=db dbname=app)
+	if isKeywordDSN(dsn) {
+		return "pgx", dsn
+	}
+
 	// Fallback: check for go-sql-driver/mysql format (user:password@tcp(host:port)/database)
 	if strings.Contains(dsn, "@tcp(") || strings.Contains(dsn, "@unix(") {
 		return "mysql", dsn
@@ -58,9 +67,53 @@ func (Credentials) parse(dsn string) (string, string) {
 	return "sqlite", dsn
 }
 
+// keywordDSN matches the key=value form of postgres DSNs.
+var keywordDSN = regexp.MustCompile(`^\s*(host|hostaddr|port|dbname|user|password|sslmode|service)\s*=`)
+
+// isKeywordDSN returns true for postgres DSNs in the key=value form,
+// e.g. `host=db dbname=app user=app`, rather than the URL form.
+func isKeywordDSN(dsn string) bool {
+	return keywordDSN.MatchString(dsn)
+}
+
+// keywordValue quotes a value for the key=value form of postgres DSNs.
+func keywordValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
 func addOptionToDSN(dsn, match, option string) string {
 	if !strings.Contains(dsn, match) {
 		dsn += option
 	}
 	return dsn
 }
+
+// passwordParam matches password parameters of a DSN, in the query string
+// of URLs and MySQL DSNs, and in the key=value form of Postgres, where the
+// value may be quoted.
+var passwordParam = regexp.MustCompile(`(?i)(^|[?&\s])(password|passwd|pass|pwd|sslpassword)=('(?:[^'\\]|\\.)*'|[^&\s]*)`)
+
+// Redacted returns the DSN with the passwords replaced with `xxxxx`, both
+// in the user info (`user:pass@`) and in password parameters
+// (`?password=...`, `password=...`).
+func (c Credentials) Redacted() string {
+	dsn, scheme := c.DSN, ""
+	if idx := strings.Index(dsn, "://"); idx >= 0 {
+		scheme, dsn = dsn[:idx+3], dsn[idx+3:]
+	}
+
+	// The user info is before the query string, which may contain `@`.
+	dsn, query, hasQuery := strings.Cut(dsn, "?")
+	if at := strings.LastIndex(dsn, "@"); at >= 0 {
+		if user, _, ok := strings.Cut(dsn[:at], ":"); ok {
+			dsn = user + ":xxxxx" + dsn[at:]
+		}
+	}
+	if hasQuery {
+		dsn += "?" + query
+	}
+
+	return passwordParam.ReplaceAllString(scheme+dsn, "${1}${2}=xxxxx")
+}
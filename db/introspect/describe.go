@@ -32,6 +32,18 @@ type Describer interface {
 	TableIndexes(ctx context.Context, db *sqlx.DB, tableName string) ([]*model.Index, error)
 }
 
+// ExtDescriber is implemented by the describers returned from NewDescriber.
+// It provides the Describer methods for any sqlx.ExtContext, e.g. a *sqlx.Tx
+// to inspect schema changes that haven't been committed yet.
+type ExtDescriber interface {
+	Describer
+
+	DescribeExt(ctx context.Context, db sqlx.ExtContext, query string) ([]*model.Column, error)
+	DescribeTableExt(ctx context.Context, db sqlx.ExtContext, tableName string) (*model.Table, error)
+	ListTablesExt(ctx context.Context, db sqlx.ExtContext) ([]*model.Table, error)
+	TableIndexesExt(ctx context.Context, db sqlx.ExtContext, tableName string) ([]*model.Index, error)
+}
+
 // ListTablesWithColumns returns all tables with their columns populated and indexes sorted.
 // For each table returned by ListTables, it calls DescribeTable to fetch column information.
 // If a table comment is empty, it's filled with a title-cased version of the table name.
@@ -69,6 +81,12 @@ func ListTablesWithColumns(ctx context.Context, db *sqlx.DB, describer Describer
 // NewDescriber returns a Describer implementation for the given database connection.
 // The driver type is determined from the database connection's DriverName().
 func NewDescriber(db *sqlx.DB) (Describer, error) {
+	return NewExtDescriber(db)
+}
+
+// NewExtDescriber returns an ExtDescriber implementation for the driver of db,
+// e.g. to describe the schema within a transaction.
+func NewExtDescriber(db sqlx.ExtContext) (ExtDescriber, error) {
 	driverName := db.DriverName()
 	switch driverName {
 	case "sqlite":
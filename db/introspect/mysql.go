@@ -13,7 +13,7 @@ import (
 )
 
 // enrichKeysFromInfoSchema retrieves type, key, and comment from information_schema.
-func enrichKeysFromInfoSchema(ctx context.Context, db *sqlx.DB, tableName string, columns []*model.Column) {
+func enrichKeysFromInfoSchema(ctx context.Context, db sqlx.ExtContext, tableName string, columns []*model.Column) {
 	var schemaColumns []*model.Column
 
 	// Query information_schema for complete column information
@@ -23,7 +23,7 @@ func enrichKeysFromInfoSchema(ctx context.Context, db *sqlx.DB, tableName string
 		fields,
 	)
 
-	if err := db.SelectContext(ctx, &schemaColumns, query, tableName); err != nil {
+	if err := sqlx.SelectContext(ctx, db, &schemaColumns, query, tableName); err != nil {
 		// Silently ignore errors - we'll use DESCRIBE information as fallback
 		return
 	}
@@ -57,6 +57,11 @@ type MysqlDescriber struct{}
 
 // Describe returns column metadata from a query.
 func (d *MysqlDescriber) Describe(ctx context.Context, db *sqlx.DB, query string) ([]*model.Column, error) {
+	return d.DescribeExt(ctx, db, query)
+}
+
+// DescribeExt is Describe for a sqlx.ExtContext, e.g. a *sqlx.Tx.
+func (d *MysqlDescriber) DescribeExt(ctx context.Context, db sqlx.ExtContext, query string) ([]*model.Column, error) {
 	var err error
 
 	// Normalize query
@@ -94,7 +99,7 @@ func (d *MysqlDescriber) Describe(ctx context.Context, db *sqlx.DB, query string
 
 	var describeRows []describeRow
 	describeQuery := fmt.Sprintf("DESCRIBE `%s`", tableName)
-	if err = db.SelectContext(ctx, &describeRows, describeQuery); err != nil {
+	if err = sqlx.SelectContext(ctx, db, &describeRows, describeQuery); err != nil {
 		return nil, errors.Wrap(err, "failed to describe temporary table")
 	}
 
@@ -123,6 +128,11 @@ func (d *MysqlDescriber) Describe(ctx context.Context, db *sqlx.DB, query string
 
 // DescribeTable returns the structure of a table.
 func (d *MysqlDescriber) DescribeTable(ctx context.Context, db *sqlx.DB, tableName string) (*model.Table, error) {
+	return d.DescribeTableExt(ctx, db, tableName)
+}
+
+// DescribeTableExt is DescribeTable for a sqlx.ExtContext, e.g. a *sqlx.Tx.
+func (d *MysqlDescriber) DescribeTableExt(ctx context.Context, db sqlx.ExtContext, tableName string) (*model.Table, error) {
 	table := &model.Table{
 		Name: tableName,
 	}
@@ -132,7 +142,7 @@ func (d *MysqlDescriber) DescribeTable(ctx context.Context, db *sqlx.DB, tableNa
 		Comment string `db:"TABLE_COMMENT"`
 	}
 	var tr tableRow
-	if err := db.GetContext(ctx, &tr, "SELECT TABLE_COMMENT FROM information_schema.tables WHERE table_schema=DATABASE() AND table_name=?", tableName); err != nil {
+	if err := sqlx.GetContext(ctx, db, &tr, "SELECT TABLE_COMMENT FROM information_schema.tables WHERE table_schema=DATABASE() AND table_name=?", tableName); err != nil {
 		return nil, errors.Wrapf(err, "failed to get table comment for %s", tableName)
 	}
 	table.Comment = tr.Comment
@@ -145,7 +155,7 @@ func (d *MysqlDescriber) DescribeTable(ctx context.Context, db *sqlx.DB, tableNa
 		fields,
 	)
 
-	if err := db.SelectContext(ctx, &columns, query, tableName); err != nil {
+	if err := sqlx.SelectContext(ctx, db, &columns, query, tableName); err != nil {
 		return nil, errors.Wrapf(err, "failed to get columns for table %s", tableName)
 	}
 
@@ -158,7 +168,7 @@ func (d *MysqlDescriber) DescribeTable(ctx context.Context, db *sqlx.DB, tableNa
 	}
 
 	// Get indexes for this table
-	indexes, err := d.TableIndexes(ctx, db, tableName)
+	indexes, err := d.TableIndexesExt(ctx, db, tableName)
 	if err != nil {
 		return nil, err
 	}
@@ -173,12 +183,17 @@ func (d *MysqlDescriber) DescribeTable(ctx context.Context, db *sqlx.DB, tableNa
 
 // ListTables returns all tables without columns populated.
 func (d *MysqlDescriber) ListTables(ctx context.Context, db *sqlx.DB) ([]*model.Table, error) {
+	return d.ListTablesExt(ctx, db)
+}
+
+// ListTablesExt is ListTables for a sqlx.ExtContext, e.g. a *sqlx.Tx.
+func (d *MysqlDescriber) ListTablesExt(ctx context.Context, db sqlx.ExtContext) ([]*model.Table, error) {
 	const tableType = "BASE TABLE"
 
 	tables := []*model.Table{}
 
 	// Get all base tables (excluding views)
-	if err := db.SelectContext(ctx, &tables, "SELECT TABLE_NAME, TABLE_COMMENT FROM information_schema.tables WHERE table_schema=DATABASE() AND table_type=? ORDER BY table_name ASC", tableType); err != nil {
+	if err := sqlx.SelectContext(ctx, db, &tables, "SELECT TABLE_NAME, TABLE_COMMENT FROM information_schema.tables WHERE table_schema=DATABASE() AND table_type=? ORDER BY table_name ASC", tableType); err != nil {
 		return nil, errors.Wrap(err, "failed to list tables")
 	}
 
@@ -187,6 +202,11 @@ func (d *MysqlDescriber) ListTables(ctx context.Context, db *sqlx.DB) ([]*model.
 
 // TableIndexes returns all indexes for a MySQL table.
 func (d *MysqlDescriber) TableIndexes(ctx context.Context, db *sqlx.DB, tableName string) ([]*model.Index, error) {
+	return d.TableIndexesExt(ctx, db, tableName)
+}
+
+// TableIndexesExt is TableIndexes for a sqlx.ExtContext, e.g. a *sqlx.Tx.
+func (d *MysqlDescriber) TableIndexesExt(ctx context.Context, db sqlx.ExtContext, tableName string) ([]*model.Index, error) {
 	type indexStatistic struct {
 		IndexName  string `db:"INDEX_NAME"`
 		ColumnList string `db:"COLUMN_LIST"`
@@ -205,7 +225,7 @@ func (d *MysqlDescriber) TableIndexes(ctx context.Context, db *sqlx.DB, tableNam
 		ORDER BY INDEX_NAME
 	`
 
-	if err := db.SelectContext(ctx, &indexStats, query, tableName); err != nil {
+	if err := sqlx.SelectContext(ctx, db, &indexStats, query, tableName); err != nil {
 		return nil, errors.Wrapf(err, "failed to get indexes for table %s", tableName)
 	}
 
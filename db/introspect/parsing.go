@@ -35,9 +35,20 @@ func parseSqliteType(column *model.Column) {
 			column.Size = 8
 		}
 	}
+
+	if matches := numericPattern.FindStringSubmatch(typeStr); len(matches) >= 4 {
+		column.Size, column.Scale = parsePrecision(matches)
+	}
+}
+
+// parsePrecision returns the precision and scale from numericPattern matches.
+func parsePrecision(matches []string) (int, int) {
+	precision, _ := strconv.Atoi(matches[2])
+	scale, _ := strconv.Atoi(matches[3])
+	return precision, scale
 }
 
-func parsePostgresType(ctx context.Context, db *sqlx.DB, column *model.Column) {
+func parsePostgresType(ctx context.Context, db sqlx.ExtContext, column *model.Column) {
 	typeStr := strings.ToLower(strings.TrimSpace(column.Type))
 	column.DataType = typeStr
 
@@ -90,13 +101,11 @@ func parseMySQLType(column *model.Column) {
 		return
 	}
 
-	// Handle numeric/decimal - extract precision
-	if matches := numericPattern.FindStringSubmatch(typeStr); len(matches) >= 3 {
+	// Handle numeric/decimal - extract precision and scale
+	if matches := numericPattern.FindStringSubmatch(typeStr); len(matches) >= 4 {
 		column.Type = "decimal"
 		column.DataType = matches[1]
-		if n, err := strconv.Atoi(matches[2]); err == nil {
-			column.Size = n
-		}
+		column.Size, column.Scale = parsePrecision(matches)
 		return
 	}
 
@@ -18,6 +18,11 @@ type PostgresDescriber struct{}
 
 // Describe returns column metadata from a query.
 func (d *PostgresDescriber) Describe(ctx context.Context, db *sqlx.DB, query string) ([]*model.Column, error) {
+	return d.DescribeExt(ctx, db, query)
+}
+
+// DescribeExt is Describe for a sqlx.ExtContext, e.g. a *sqlx.Tx.
+func (d *PostgresDescriber) DescribeExt(ctx context.Context, db sqlx.ExtContext, query string) ([]*model.Column, error) {
 	var err error
 
 	// Normalize query
@@ -78,7 +83,7 @@ func (d *PostgresDescriber) Describe(ctx context.Context, db *sqlx.DB, query str
 
 	pgCols := []pgColumn{}
 	columns := []*model.Column{}
-	if err = db.SelectContext(ctx, &pgCols, pgQuery, viewName); err != nil {
+	if err = sqlx.SelectContext(ctx, db, &pgCols, pgQuery, viewName); err != nil {
 		return nil, errors.Wrap(err, "failed to query column metadata from information_schema")
 	}
 
@@ -108,13 +113,18 @@ func (d *PostgresDescriber) Describe(ctx context.Context, db *sqlx.DB, query str
 
 // DescribeTable returns the structure of a table.
 func (d *PostgresDescriber) DescribeTable(ctx context.Context, db *sqlx.DB, tableName string) (*model.Table, error) {
+	return d.DescribeTableExt(ctx, db, tableName)
+}
+
+// DescribeTableExt is DescribeTable for a sqlx.ExtContext, e.g. a *sqlx.Tx.
+func (d *PostgresDescriber) DescribeTableExt(ctx context.Context, db sqlx.ExtContext, tableName string) (*model.Table, error) {
 	table := &model.Table{
 		Name: tableName,
 	}
 
 	// Get table comment from pg_description
 	var comment *string
-	if err := db.GetContext(ctx, &comment, `
+	if err := sqlx.GetContext(ctx, db, &comment, `
 		SELECT description FROM pg_description 
 		WHERE objoid = (SELECT oid FROM pg_class WHERE relname = $1)
 		AND objsubid = 0
@@ -132,7 +142,8 @@ func (d *PostgresDescriber) DescribeTable(ctx context.Context, db *sqlx.DB, tabl
 		SELECT 
 			c.column_name as "COLUMN_NAME",
 			c.udt_name as "COLUMN_TYPE",
-			COALESCE(c.character_maximum_length, 0) as "SIZE",
+			CASE WHEN c.udt_name = 'numeric' THEN COALESCE(c.numeric_precision, 0) ELSE COALESCE(c.character_maximum_length, 0) END as "SIZE",
+			CASE WHEN c.udt_name = 'numeric' THEN COALESCE(c.numeric_scale, 0) ELSE 0 END as "SCALE",
 			COALESCE(col_description(cl.oid, c.ordinal_position), '') as "COLUMN_COMMENT",
 			c.udt_name as "DATA_TYPE",
 			CASE WHEN pk.conname IS NOT NULL AND a.attnum = ANY(pk.conkey) THEN 'PRI' ELSE '' END as "COLUMN_KEY"
@@ -144,7 +155,7 @@ func (d *PostgresDescriber) DescribeTable(ctx context.Context, db *sqlx.DB, tabl
 		ORDER BY c.ordinal_position
 	`
 
-	if err := db.SelectContext(ctx, &columns, query, tableName); err != nil {
+	if err := sqlx.SelectContext(ctx, db, &columns, query, tableName); err != nil {
 		return nil, errors.Wrapf(err, "failed to get columns for table %s", tableName)
 	}
 
@@ -159,7 +170,7 @@ func (d *PostgresDescriber) DescribeTable(ctx context.Context, db *sqlx.DB, tabl
 	}
 
 	// Get indexes for this table
-	indexes, err := d.TableIndexes(ctx, db, tableName)
+	indexes, err := d.TableIndexesExt(ctx, db, tableName)
 	if err != nil {
 		return nil, err
 	}
@@ -174,10 +185,15 @@ func (d *PostgresDescriber) DescribeTable(ctx context.Context, db *sqlx.DB, tabl
 
 // ListTables returns all tables without columns populated.
 func (d *PostgresDescriber) ListTables(ctx context.Context, db *sqlx.DB) ([]*model.Table, error) {
+	return d.ListTablesExt(ctx, db)
+}
+
+// ListTablesExt is ListTables for a sqlx.ExtContext, e.g. a *sqlx.Tx.
+func (d *PostgresDescriber) ListTablesExt(ctx context.Context, db sqlx.ExtContext) ([]*model.Table, error) {
 	tables := []*model.Table{}
 
 	// Get all tables in current schema (excluding system tables)
-	if err := db.SelectContext(ctx, &tables, `
+	if err := sqlx.SelectContext(ctx, db, &tables, `
 		SELECT 
 			c.relname as "TABLE_NAME",
 			COALESCE(d.description, '') as "TABLE_COMMENT"
@@ -195,6 +211,11 @@ func (d *PostgresDescriber) ListTables(ctx context.Context, db *sqlx.DB) ([]*mod
 
 // TableIndexes returns all indexes for a table.
 func (d *PostgresDescriber) TableIndexes(ctx context.Context, db *sqlx.DB, tableName string) ([]*model.Index, error) {
+	return d.TableIndexesExt(ctx, db, tableName)
+}
+
+// TableIndexesExt is TableIndexes for a sqlx.ExtContext, e.g. a *sqlx.Tx.
+func (d *PostgresDescriber) TableIndexesExt(ctx context.Context, db sqlx.ExtContext, tableName string) ([]*model.Index, error) {
 	type indexInfo struct {
 		Name    string         `db:"name"`
 		Columns pq.StringArray `db:"columns"`
@@ -218,7 +239,7 @@ func (d *PostgresDescriber) TableIndexes(ctx context.Context, db *sqlx.DB, table
 		ORDER BY i.relname
 	`
 
-	if err := db.SelectContext(ctx, &indexInfos, query, tableName); err != nil {
+	if err := sqlx.SelectContext(ctx, db, &indexInfos, query, tableName); err != nil {
 		return nil, errors.Wrapf(err, "failed to get indexes for table %s", tableName)
 	}
 
@@ -236,7 +257,7 @@ func (d *PostgresDescriber) TableIndexes(ctx context.Context, db *sqlx.DB, table
 }
 
 // extractPostgresEnumValues fetches the allowed values for an ENUM type.
-func extractPostgresEnumValues(ctx context.Context, db *sqlx.DB, enumTypeName string) []string {
+func extractPostgresEnumValues(ctx context.Context, db sqlx.ExtContext, enumTypeName string) []string {
 	var values []string
 
 	// Query pg_enum to get all values for this enum type
@@ -246,7 +267,7 @@ func extractPostgresEnumValues(ctx context.Context, db *sqlx.DB, enumTypeName st
 		ORDER BY enumsortorder
 	`
 
-	if err := db.SelectContext(ctx, &values, query, enumTypeName); err != nil {
+	if err := sqlx.SelectContext(ctx, db, &values, query, enumTypeName); err != nil {
 		// Silently ignore if we can't fetch enum values
 		return nil
 	}
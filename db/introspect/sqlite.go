@@ -18,6 +18,11 @@ type SqliteDescriber struct{}
 
 // Describe returns column metadata from a query.
 func (d *SqliteDescriber) Describe(ctx context.Context, db *sqlx.DB, query string) ([]*model.Column, error) {
+	return d.DescribeExt(ctx, db, query)
+}
+
+// DescribeExt is Describe for a sqlx.ExtContext, e.g. a *sqlx.Tx.
+func (d *SqliteDescriber) DescribeExt(ctx context.Context, db sqlx.ExtContext, query string) ([]*model.Column, error) {
 	var err error
 
 	// Normalize query
@@ -52,7 +57,7 @@ func (d *SqliteDescriber) Describe(ctx context.Context, db *sqlx.DB, query strin
 	}
 
 	var pragmaColumns []pragmaColumn
-	if err := db.SelectContext(ctx, &pragmaColumns, fmt.Sprintf("PRAGMA table_info(%s)", viewName)); err != nil {
+	if err := sqlx.SelectContext(ctx, db, &pragmaColumns, fmt.Sprintf("PRAGMA table_info(%s)", viewName)); err != nil {
 		return nil, errors.Wrap(err, "failed to query column metadata with PRAGMA table_info")
 	}
 
@@ -89,6 +94,11 @@ func (d *SqliteDescriber) Describe(ctx context.Context, db *sqlx.DB, query strin
 
 // DescribeTable returns the structure of a table.
 func (d *SqliteDescriber) DescribeTable(ctx context.Context, db *sqlx.DB, tableName string) (*model.Table, error) {
+	return d.DescribeTableExt(ctx, db, tableName)
+}
+
+// DescribeTableExt is DescribeTable for a sqlx.ExtContext, e.g. a *sqlx.Tx.
+func (d *SqliteDescriber) DescribeTableExt(ctx context.Context, db sqlx.ExtContext, tableName string) (*model.Table, error) {
 	table := &model.Table{
 		Name:    tableName,
 		Comment: "", // SQLite doesn't have table comments
@@ -105,7 +115,7 @@ func (d *SqliteDescriber) DescribeTable(ctx context.Context, db *sqlx.DB, tableN
 	}
 
 	var pragmaColumns []pragmaColumn
-	if err := db.SelectContext(ctx, &pragmaColumns, fmt.Sprintf("PRAGMA table_info(%s)", tableName)); err != nil {
+	if err := sqlx.SelectContext(ctx, db, &pragmaColumns, fmt.Sprintf("PRAGMA table_info(%s)", tableName)); err != nil {
 		return nil, errors.Wrapf(err, "failed to query table info for %s", tableName)
 	}
 
@@ -148,7 +158,7 @@ func (d *SqliteDescriber) DescribeTable(ctx context.Context, db *sqlx.DB, tableN
 	}
 
 	// Get indexes for this table
-	indexes, err := d.TableIndexes(ctx, db, tableName)
+	indexes, err := d.TableIndexesExt(ctx, db, tableName)
 	if err != nil {
 		return nil, err
 	}
@@ -163,10 +173,15 @@ func (d *SqliteDescriber) DescribeTable(ctx context.Context, db *sqlx.DB, tableN
 
 // ListTables returns all tables without columns populated.
 func (d *SqliteDescriber) ListTables(ctx context.Context, db *sqlx.DB) ([]*model.Table, error) {
+	return d.ListTablesExt(ctx, db)
+}
+
+// ListTablesExt is ListTables for a sqlx.ExtContext, e.g. a *sqlx.Tx.
+func (d *SqliteDescriber) ListTablesExt(ctx context.Context, db sqlx.ExtContext) ([]*model.Table, error) {
 	tables := []*model.Table{}
 
 	// Get all user-defined tables (excluding sqlite internal tables)
-	if err := db.SelectContext(ctx, &tables, "SELECT name as TABLE_NAME, '' as TABLE_COMMENT FROM sqlite_schema WHERE type='table' AND name NOT LIKE 'sqlite_%' ORDER BY name ASC"); err != nil {
+	if err := sqlx.SelectContext(ctx, db, &tables, "SELECT name as TABLE_NAME, '' as TABLE_COMMENT FROM sqlite_schema WHERE type='table' AND name NOT LIKE 'sqlite_%' ORDER BY name ASC"); err != nil {
 		return nil, errors.Wrap(err, "failed to list tables")
 	}
 
@@ -175,6 +190,11 @@ func (d *SqliteDescriber) ListTables(ctx context.Context, db *sqlx.DB) ([]*model
 
 // TableIndexes returns all indexes for a table, synthesizing the primary key from table_info.
 func (d *SqliteDescriber) TableIndexes(ctx context.Context, db *sqlx.DB, tableName string) ([]*model.Index, error) {
+	return d.TableIndexesExt(ctx, db, tableName)
+}
+
+// TableIndexesExt is TableIndexes for a sqlx.ExtContext, e.g. a *sqlx.Tx.
+func (d *SqliteDescriber) TableIndexesExt(ctx context.Context, db sqlx.ExtContext, tableName string) ([]*model.Index, error) {
 	// Get all indexes for the table (PRAGMA doesn't support parameterized queries)
 	type indexInfo struct {
 		Seq     int    `db:"seq"`
@@ -185,7 +205,7 @@ func (d *SqliteDescriber) TableIndexes(ctx context.Context, db *sqlx.DB, tableNa
 	}
 
 	var indexInfos []indexInfo
-	if err := db.SelectContext(ctx, &indexInfos, fmt.Sprintf("PRAGMA index_list(%s)", tableName)); err != nil {
+	if err := sqlx.SelectContext(ctx, db, &indexInfos, fmt.Sprintf("PRAGMA index_list(%s)", tableName)); err != nil {
 		return nil, errors.Wrapf(err, "failed to get indexes for table %s", tableName)
 	}
 
@@ -199,7 +219,7 @@ func (d *SqliteDescriber) TableIndexes(ctx context.Context, db *sqlx.DB, tableNa
 		}
 
 		var indexColumns []indexColumn
-		if err := db.SelectContext(ctx, &indexColumns, fmt.Sprintf("PRAGMA index_info(%s)", ii.Name)); err != nil {
+		if err := sqlx.SelectContext(ctx, db, &indexColumns, fmt.Sprintf("PRAGMA index_info(%s)", ii.Name)); err != nil {
 			continue
 		}
 
@@ -228,7 +248,7 @@ func (d *SqliteDescriber) TableIndexes(ctx context.Context, db *sqlx.DB, tableNa
 	}
 
 	var pragmaColumns []pragmaColumn
-	if err := db.SelectContext(ctx, &pragmaColumns, fmt.Sprintf("PRAGMA table_info(%s)", tableName)); err != nil {
+	if err := sqlx.SelectContext(ctx, db, &pragmaColumns, fmt.Sprintf("PRAGMA table_info(%s)", tableName)); err != nil {
 		return indexes, nil // Return what we have if we can't get primary key info
 	}
 
@@ -266,12 +286,12 @@ func (d *SqliteDescriber) TableIndexes(ctx context.Context, db *sqlx.DB, tableNa
 
 // extractSqliteCheckConstraints extracts CHECK constraints from table definition
 // Returns a map of column name to list of CHECK constraint expressions
-func extractSqliteCheckConstraints(ctx context.Context, db *sqlx.DB, tableName string) map[string][]string {
+func extractSqliteCheckConstraints(ctx context.Context, db sqlx.ExtContext, tableName string) map[string][]string {
 	constraintMap := make(map[string][]string)
 
 	// Get table creation SQL from sqlite_schema
 	var sql string
-	err := db.GetContext(ctx, &sql, "SELECT sql FROM sqlite_schema WHERE type='table' AND name=?", tableName)
+	err := sqlx.GetContext(ctx, db, &sql, "SELECT sql FROM sqlite_schema WHERE type='table' AND name=?", tableName)
 	if err != nil || sql == "" {
 		return constraintMap
 	}
@@ -301,9 +321,10 @@ func extractSqliteCheckConstraints(ctx context.Context, db *sqlx.DB, tableName s
 func extractColumnNameFromCheckLine(line string) string {
 	// Get the first token in the line (should be the column name)
 	// Format is typically: column_name TYPE ... CHECK (...)
+	// The name may be quoted, e.g. "column_name" or `column_name`.
 	fields := strings.Fields(line)
 	if len(fields) > 0 {
-		return fields[0]
+		return strings.Trim(fields[0], "\"`[]")
 	}
 	return ""
 }
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// WithSearchPath returns postgres credentials with the search_path set to
+// schema for every connection. Tables are created in, and resolved from,
+// the schema only. Both the URL and the key=value DSN forms are supported.
+func (c Credentials) WithSearchPath(schema string) (Credentials, error) {
+	driver, dsn := c.Open()
+	if driver != "pgx" {
+		return c, fmt.Errorf("search_path is only supported for postgres, got %s", driver)
+	}
+
+	// A repeated key overrides the previous value.
+	if isKeywordDSN(dsn) {
+		return NewCredentials(c.DSN + " search_path=" + keywordValue(quoteIdentifier(schema))), nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return c, fmt.Errorf("error parsing DSN: %w", err)
+	}
+
+	query := u.Query()
+	query.Set("search_path", quoteIdentifier(schema))
+	u.RawQuery = query.Encode()
+
+	return NewCredentials(u.String()), nil
+}
+
+// WithDatabase returns mysql or postgres credentials connecting to the
+// database name on the same server.
+func (c Credentials) WithDatabase(name string) (Credentials, error) {
+	driver, dsn := c.parse(c.DSN)
+	switch driver {
+	case "pgx":
+		if isKeywordDSN(dsn) {
+			return NewCredentials(c.DSN + " dbname=" + keywordValue(name)), nil
+		}
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return c, fmt.Errorf("error parsing DSN: %w", err)
+		}
+		u.Path = "/" + name
+		return NewCredentials(u.String()), nil
+	case "mysql":
+		// user:password@tcp(host:port)/database?options
+		slash := strings.LastIndex(dsn, ")/")
+		if slash < 0 {
+			return c, fmt.Errorf("error parsing DSN: missing database")
+		}
+		rest := dsn[slash+2:]
+		query := ""
+		if idx := strings.Index(rest, "?"); idx >= 0 {
+			query = rest[idx:]
+		}
+		return NewCredentials("mysql://" + dsn[:slash+2] + name + query), nil
+	}
+	return c, fmt.Errorf("changing the database is only supported for mysql and postgres, got %s", driver)
+}
+
+// ListSchemas returns the postgres schemas matching a LIKE pattern, e.g. `tenant_%`.
+func ListSchemas(ctx context.Context, sqldb *sqlx.DB, pattern string) ([]string, error) {
+	result := []string{}
+	query := "SELECT schema_name FROM information_schema.schemata WHERE schema_name LIKE $1 ORDER BY schema_name"
+	if err := sqldb.SelectContext(ctx, &result, query, pattern); err != nil {
+		return nil, fmt.Errorf("error listing schemas: %w", err)
+	}
+	return result, nil
+}
+
+// ListSchemasFromTable returns the schema names stored in a table column.
+func ListSchemasFromTable(ctx context.Context, sqldb *sqlx.DB, table, column string) ([]string, error) {
+	result := []string{}
+	query := fmt.Sprintf("SELECT DISTINCT %s FROM %s ORDER BY 1", quoteIdentifier(column), quoteQualified(table))
+	if err := sqldb.SelectContext(ctx, &result, query); err != nil {
+		return nil, fmt.Errorf("error listing schemas from %s: %w", table, err)
+	}
+	return result, nil
+}
+
+// quoteIdentifier quotes a postgres identifier.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteQualified quotes each part of a schema qualified identifier (`schema.table`).
+func quoteQualified(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SetSessionTimeouts applies statement and lock timeouts to the database session.
+// The settings only apply to the connection they are issued on, so the execer
+// should be a *sqlx.Conn that's used for the following statements.
+// A zero duration leaves the server default in place.
+func SetSessionTimeouts(ctx context.Context, execer sqlx.ExecerContext, driverName string, statementTimeout, lockTimeout time.Duration) error {
+	queries := []string{}
+
+	switch driverName {
+	case "postgres", "postgresql", "pgx":
+		if lockTimeout > 0 {
+			queries = append(queries, fmt.Sprintf("SET lock_timeout = %d", lockTimeout.Milliseconds()))
+		}
+		if statementTimeout > 0 {
+			queries = append(queries, fmt.Sprintf("SET statement_timeout = %d", statementTimeout.Milliseconds()))
+		}
+
+	case "mysql":
+		// lock_wait_timeout covers metadata locks taken by DDL,
+		// innodb_lock_wait_timeout covers row locks taken by DML.
+		// Both are set in seconds, with a minimum of 1.
+		if lockTimeout > 0 {
+			seconds := max(int64(lockTimeout.Seconds()), 1)
+			queries = append(queries,
+				fmt.Sprintf("SET SESSION lock_wait_timeout = %d", seconds),
+				fmt.Sprintf("SET SESSION innodb_lock_wait_timeout = %d", seconds),
+			)
+		}
+		// max_execution_time only applies to read-only SELECT
+		// statements, DDL and DML statements aren't limited.
+		if statementTimeout > 0 {
+			queries = append(queries, fmt.Sprintf("SET SESSION max_execution_time = %d", statementTimeout.Milliseconds()))
+		}
+
+	case "sqlite":
+		// SQLite has no statement timeout, callers should bound
+		// statements with a context deadline instead.
+		if lockTimeout > 0 {
+			queries = append(queries, fmt.Sprintf("PRAGMA busy_timeout = %d", lockTimeout.Milliseconds()))
+		}
+
+	default:
+		if statementTimeout > 0 || lockTimeout > 0 {
+			return fmt.Errorf("session timeouts not supported for driver: %s", driverName)
+		}
+	}
+
+	for _, query := range queries {
+		if _, err := execer.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to set session timeout (%s): %w", query, err)
+		}
+	}
+	return nil
+}
+
+// sqlitePragmas are the connection settings restored by SaveSQLiteSession.
+var sqlitePragmas = []string{
+	"automatic_index",
+	"busy_timeout",
+	"cache_size",
+	"defer_foreign_keys",
+	"foreign_keys",
+	"ignore_check_constraints",
+	"query_only",
+	"recursive_triggers",
+	"reverse_unordered_selects",
+	"synchronous",
+	"temp_store",
+}
+
+// SaveSQLiteSession reads the connection settings (pragmas) and the attached
+// databases of a sqlite connection, and returns a function restoring them.
+// Settings changed on a connection stay on it when it's returned to the
+// pool, so they should be restored before the connection is released.
+// Closing the connection instead would drop an in-memory database.
+func SaveSQLiteSession(ctx context.Context, conn *sqlx.Conn) (func(context.Context) error, error) {
+	queries := make([]string, 0, len(sqlitePragmas))
+	for _, name := range sqlitePragmas {
+		var value string
+		if err := conn.GetContext(ctx, &value, "PRAGMA "+name); err != nil {
+			return nil, fmt.Errorf("failed to read session setting %s: %w", name, err)
+		}
+		queries = append(queries, fmt.Sprintf("PRAGMA %s = %s", name, value))
+	}
+
+	attached, err := sqliteDatabases(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) error {
+		databases, err := sqliteDatabases(ctx, conn)
+		if err != nil {
+			return err
+		}
+		restore := slices.Clone(queries)
+		for _, name := range databases {
+			// The temp database is created on use, and can't be detached.
+			if name != "temp" && !slices.Contains(attached, name) {
+				restore = append(restore, "DETACH DATABASE "+quoteIdentifier(name))
+			}
+		}
+
+		for _, query := range restore {
+			if _, err := conn.ExecContext(ctx, query); err != nil {
+				return fmt.Errorf("failed to restore session (%s): %w", query, err)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// sqliteDatabases returns the names of the databases of a sqlite connection.
+func sqliteDatabases(ctx context.Context, conn *sqlx.Conn) ([]string, error) {
+	result := []string{}
+	if err := conn.SelectContext(ctx, &result, "SELECT name FROM pragma_database_list"); err != nil {
+		return nil, fmt.Errorf("failed to list attached databases: %w", err)
+	}
+	return result, nil
+}
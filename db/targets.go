@@ -0,0 +1,312 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+// TenantPlaceholder is replaced with the tenant name in a DSN template.
+const TenantPlaceholder = "{tenant}"
+
+// Targets include options for running against multiple databases.
+// DSNs are collected from all the sources: repeated dsn flags, a DSN
+// file and a DSN template expanded for each tenant.
+type Targets struct {
+	// DSN holds DSN values, the flag may be repeated.
+	DSN []string
+
+	// DSNFile is a file with a DSN on each line.
+	DSNFile string
+
+	// Template is a DSN with a `{tenant}` placeholder.
+	Template string
+
+	// Tenants holds tenant names for Template.
+	Tenants []string
+
+	// TenantsFile is a file with a tenant name on each line.
+	TenantsFile string
+
+	// SchemaPattern expands each postgres target to the schemas
+	// matching a LIKE pattern, e.g. `tenant_%`.
+	SchemaPattern string
+
+	// SchemaTable expands each postgres target to the schemas listed
+	// in SchemaColumn of a table, e.g. `public.tenants`.
+	SchemaTable  string
+	SchemaColumn string
+
+	// Concurrency limits the number of databases processed at the same time.
+	Concurrency int
+
+	// ContinueOnError keeps processing the remaining databases after an error.
+	// By default, no new databases are started after the first error.
+	ContinueOnError bool
+}
+
+// Target is a database from Targets.
+type Target struct {
+	// Name is the tenant name, or the DSN with the password redacted.
+	// For schema targets, the schema name is appended.
+	Name string
+
+	// Schema is the postgres schema set as the search_path.
+	Schema string
+
+	Options *Options
+}
+
+// TargetResult holds the outcome of processing a Target.
+type TargetResult struct {
+	Target *Target
+
+	// Skipped is true if the target wasn't processed after an error.
+	Skipped bool
+
+	Duration time.Duration
+	Err      error
+}
+
+// NewTargets provides an initialized *Targets object.
+func NewTargets() *Targets {
+	return &Targets{
+		SchemaColumn: "schema_name",
+		Concurrency:  1,
+	}
+}
+
+// Bind registers target flags on the given FlagSet with `db` prefix.
+func (targets *Targets) Bind(fs *flag.FlagSet) *Targets {
+	return targets.BindWithPrefix(fs, "db")
+}
+
+// BindWithPrefix registers target flags on the given FlagSet with a custom prefix.
+func (targets *Targets) BindWithPrefix(fs *flag.FlagSet, prefix string) *Targets {
+	p := func(s string) string {
+		if prefix != "" {
+			return prefix + "-" + s
+		}
+		return s
+	}
+	fs.StringArrayVar(&targets.DSN, p("dsn"), targets.DSN, "DSN for database connection (mysql://, postgres://, sqlite://, or driver-specific format), may be repeated (default $MIG_DB_DSN)")
+	fs.StringVar(&targets.DSNFile, p("dsn-file"), targets.DSNFile, "File with a DSN on each line")
+	fs.StringVar(&targets.Template, p("dsn-template"), targets.Template, "DSN template, "+TenantPlaceholder+" is replaced with each tenant name")
+	fs.StringSliceVar(&targets.Tenants, p("tenants"), targets.Tenants, "Tenant names for the DSN template (comma separated)")
+	fs.StringVar(&targets.TenantsFile, p("tenants-file"), targets.TenantsFile, "File with a tenant name on each line for the DSN template")
+	fs.StringVar(&targets.SchemaPattern, p("schema-pattern"), targets.SchemaPattern, "Run for each postgres schema matching a LIKE pattern (e.g. tenant_%)")
+	fs.StringVar(&targets.SchemaTable, p("schema-table"), targets.SchemaTable, "Run for each postgres schema listed in a table")
+	fs.StringVar(&targets.SchemaColumn, p("schema-column"), targets.SchemaColumn, "Column with schema names in the schema table")
+	fs.IntVar(&targets.Concurrency, p("concurrency"), targets.Concurrency, "Number of databases to process at the same time")
+	fs.BoolVar(&targets.ContinueOnError, p("continue-on-error"), targets.ContinueOnError, "false = stop after the first failed database, true = process all databases")
+	return targets
+}
+
+// List returns the configured targets. If no DSN is configured,
+// the MIG_DB_DSN environment variable is used.
+func (targets *Targets) List() ([]*Target, error) {
+	dsns := append([]string{}, targets.DSN...)
+	if targets.DSNFile != "" {
+		lines, err := readLines(targets.DSNFile)
+		if err != nil {
+			return nil, err
+		}
+		dsns = append(dsns, lines...)
+	}
+
+	result := []*Target{}
+	for _, dsn := range dsns {
+		credentials := NewCredentials(dsn)
+		result = append(result, newTarget(credentials.Redacted(), credentials))
+	}
+
+	tenants := append([]string{}, targets.Tenants...)
+	if targets.TenantsFile != "" {
+		lines, err := readLines(targets.TenantsFile)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, lines...)
+	}
+
+	switch {
+	case targets.Template != "" && len(tenants) == 0:
+		return nil, fmt.Errorf("DSN template requires tenants")
+	case targets.Template == "" && len(tenants) > 0:
+		return nil, fmt.Errorf("tenants require a DSN template")
+	case targets.Template != "" && !strings.Contains(targets.Template, TenantPlaceholder):
+		return nil, fmt.Errorf("DSN template doesn't contain %s", TenantPlaceholder)
+	}
+	for _, tenant := range tenants {
+		dsn := strings.ReplaceAll(targets.Template, TenantPlaceholder, tenant)
+		result = append(result, newTarget(tenant, NewCredentials(dsn)))
+	}
+
+	if len(result) == 0 {
+		dsn := os.Getenv("MIG_DB_DSN")
+		if dsn == "" {
+			return nil, ErrEmptyDSN
+		}
+		credentials := NewCredentials(dsn)
+		result = append(result, newTarget(credentials.Redacted(), credentials))
+	}
+	return result, nil
+}
+
+// ExpandSchemas replaces each target with a target for each of its schemas,
+// listed with SchemaPattern or SchemaTable. Schema targets connect with the
+// search_path set to the schema. If neither is set, list is returned as is.
+func (targets *Targets) ExpandSchemas(ctx context.Context, list []*Target) ([]*Target, error) {
+	if targets.SchemaPattern == "" && targets.SchemaTable == "" {
+		return list, nil
+	}
+
+	result := []*Target{}
+	for _, target := range list {
+		schemas, err := targets.listSchemas(ctx, target)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target.Name, err)
+		}
+
+		for _, schema := range schemas {
+			credentials, err := target.Options.Credentials.WithSearchPath(schema)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", target.Name, err)
+			}
+
+			options := *target.Options
+			options.Credentials = credentials
+			result = append(result, &Target{
+				Name:    target.Name + "/" + schema,
+				Schema:  schema,
+				Options: &options,
+			})
+		}
+	}
+	return result, nil
+}
+
+// listSchemas returns the schemas to expand a target to.
+func (targets *Targets) listSchemas(ctx context.Context, target *Target) ([]string, error) {
+	handle, err := ConnectWithRetry(ctx, target.Options)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to database: %w", err)
+	}
+	defer handle.Close()
+
+	if handle.DriverName() != "pgx" {
+		return nil, fmt.Errorf("schema targets are only supported for postgres, got %s", handle.DriverName())
+	}
+
+	result := []string{}
+	if targets.SchemaPattern != "" {
+		schemas, err := ListSchemas(ctx, handle, targets.SchemaPattern)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, schemas...)
+	}
+	if targets.SchemaTable != "" {
+		schemas, err := ListSchemasFromTable(ctx, handle, targets.SchemaTable, targets.SchemaColumn)
+		if err != nil {
+			return nil, err
+		}
+		for _, schema := range schemas {
+			if !slices.Contains(result, schema) {
+				result = append(result, schema)
+			}
+		}
+	}
+	return result, nil
+}
+
+func newTarget(name string, credentials Credentials) *Target {
+	options := NewOptions()
+	options.Credentials = credentials
+	return &Target{
+		Name:    name,
+		Options: options,
+	}
+}
+
+// Run calls fn for each target, with at most Concurrency calls running at
+// the same time. Unless ContinueOnError is set, no new targets are started
+// after fn returns an error; these targets are marked as skipped. The
+// results are returned in the order of targets.
+func (targets *Targets) Run(ctx context.Context, list []*Target, fn func(context.Context, *Target) error) []*TargetResult {
+	concurrency := max(targets.Concurrency, 1)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+		sem    = make(chan struct{}, concurrency)
+	)
+
+	result := make([]*TargetResult, len(list))
+	for idx, target := range list {
+		result[idx] = &TargetResult{
+			Target: target,
+		}
+
+		sem <- struct{}{}
+
+		mu.Lock()
+		skip := failed && !targets.ContinueOnError
+		mu.Unlock()
+
+		if skip || ctx.Err() != nil {
+			result[idx].Skipped = true
+			<-sem
+			continue
+		}
+
+		wg.Add(1)
+		go func(res *TargetResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			start := time.Now()
+			res.Err = fn(ctx, res.Target)
+			res.Duration = time.Since(start)
+
+			if res.Err != nil {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}(result[idx])
+	}
+	wg.Wait()
+
+	return result
+}
+
+// readLines returns the non-empty lines of a file, skipping `#` comments.
+func readLines(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result, scanner.Err()
+}
@@ -0,0 +1,210 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/go-bridget/mig/db/introspect"
+	"github.com/go-bridget/mig/model"
+)
+
+// assertion is a `-- mig:assert` directive, checked after the
+// statements of a migration file have been applied:
+//
+//	-- mig:assert table <table> exists|missing
+//	-- mig:assert column <table>.<column> exists|missing
+//	-- mig:assert column <table>.<column> type <type>
+//	-- mig:assert index [<table>.]<index> exists|missing|unique|primary
+type assertion struct {
+	line int
+	text string
+
+	kind   string
+	table  string
+	name   string
+	expect string
+	value  string
+}
+
+// String returns the assertion as written in the migration file.
+func (a *assertion) String() string {
+	return fmt.Sprintf("line %d: %s", a.line, a.text)
+}
+
+// assertions returns the parsed assert directives from a migration file.
+func assertions(directives []directive) ([]*assertion, error) {
+	result := []*assertion{}
+	for _, d := range directives {
+		if d.name != "assert" {
+			continue
+		}
+
+		a := &assertion{
+			line: d.line,
+			text: d.args,
+		}
+		fields := strings.Fields(d.args)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("invalid assertion on line %d: %q", d.line, d.args)
+		}
+		a.kind, a.expect = fields[0], fields[2]
+
+		target := fields[1]
+		switch a.kind {
+		case "table":
+			a.table = target
+		case "column":
+			table, column, ok := strings.Cut(target, ".")
+			if !ok {
+				return nil, fmt.Errorf("invalid assertion on line %d: expected <table>.<column>, got %q", d.line, target)
+			}
+			a.table, a.name = table, column
+		case "index":
+			if table, index, ok := strings.Cut(target, "."); ok {
+				a.table, a.name = table, index
+			} else {
+				a.name = target
+			}
+		default:
+			return nil, fmt.Errorf("invalid assertion on line %d: unknown kind %q", d.line, a.kind)
+		}
+
+		valid := map[string][]string{
+			"table":  {"exists", "missing"},
+			"column": {"exists", "missing", "type"},
+			"index":  {"exists", "missing", "unique", "primary"},
+		}
+		if !slices.Contains(valid[a.kind], a.expect) {
+			return nil, fmt.Errorf("invalid assertion on line %d: unknown %s check %q", d.line, a.kind, a.expect)
+		}
+		if a.expect == "type" {
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("invalid assertion on line %d: missing column type", d.line)
+			}
+			a.value = fields[3]
+		}
+
+		result = append(result, a)
+	}
+	return result, nil
+}
+
+// check verifies the assertion against the current database schema.
+func (a *assertion) check(ctx context.Context, q sqlx.ExtContext, describer introspect.ExtDescriber) error {
+	tables, err := describer.ListTablesExt(ctx, q)
+	if err != nil {
+		return err
+	}
+
+	var table *model.Table
+	for _, t := range tables {
+		if strings.EqualFold(t.Name, a.table) {
+			table = t
+		}
+	}
+
+	switch a.kind {
+	case "table":
+		switch {
+		case a.expect == "exists" && table == nil:
+			return a.failed("table doesn't exist")
+		case a.expect == "missing" && table != nil:
+			return a.failed("table exists")
+		}
+		return nil
+
+	case "column":
+		var column *model.Column
+		if table != nil {
+			if table, err = describer.DescribeTableExt(ctx, q, table.Name); err != nil {
+				return err
+			}
+			for _, c := range table.Columns {
+				if strings.EqualFold(c.Name, a.name) {
+					column = c
+				}
+			}
+		}
+		switch a.expect {
+		case "missing":
+			if column != nil {
+				return a.failed("column exists")
+			}
+		case "exists":
+			if column == nil {
+				return a.failed("column doesn't exist")
+			}
+		case "type":
+			if column == nil {
+				return a.failed("column doesn't exist")
+			}
+			if !strings.EqualFold(column.Type, a.value) && !strings.EqualFold(column.DataType, a.value) {
+				return a.failed("column type is %s (%s)", column.Type, column.DataType)
+			}
+		}
+		return nil
+
+	case "index":
+		if a.table != "" && table == nil {
+			return a.failed("table %s doesn't exist", a.table)
+		}
+		if table != nil {
+			tables = []*model.Table{table}
+		}
+
+		var index *model.Index
+		for _, t := range tables {
+			indexes, err := describer.TableIndexesExt(ctx, q, t.Name)
+			if err != nil {
+				return err
+			}
+			for _, idx := range indexes {
+				if strings.EqualFold(idx.Name, a.name) {
+					index = idx
+				}
+			}
+		}
+
+		switch {
+		case a.expect == "missing" && index != nil:
+			return a.failed("index exists")
+		case a.expect == "missing":
+			return nil
+		case index == nil:
+			return a.failed("index doesn't exist")
+		case a.expect == "unique" && !index.Unique:
+			return a.failed("index isn't unique")
+		case a.expect == "primary" && !index.Primary:
+			return a.failed("index isn't a primary key")
+		}
+		return nil
+	}
+	return nil
+}
+
+func (a *assertion) failed(format string, args ...interface{}) error {
+	return fmt.Errorf("assertion failed (%s): %s", a, fmt.Sprintf(format, args...))
+}
+
+// checkAssertions verifies all the assertions, returning the first failure.
+func checkAssertions(ctx context.Context, q sqlx.ExtContext, list []*assertion) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	describer, err := introspect.NewExtDescriber(q)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range list {
+		if err := a.check(ctx, q, describer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssertions(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	options := &Options{
+		Project: "test",
+		Apply:   true,
+	}
+
+	fs := FS{
+		"1-event.up.sql": []byte(`CREATE TABLE event (
+  id integer PRIMARY KEY,
+  status text NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_event_status ON event (status);
+
+-- mig:assert table event exists
+-- mig:assert table event_log missing
+-- mig:assert column event.status exists
+-- mig:assert column event.status type text
+-- mig:assert column event.kind missing
+-- mig:assert index idx_event_status unique
+-- mig:assert index event.idx_event_status exists
+`),
+	}
+	require.NoError(t, RunWithFS(ctx, db, fs, options))
+
+	// SQLite accepts any type name, the assertion catches the typo
+	fs["2-event-log.up.sql"] = []byte(`CREATE TABLE event_log (
+  id integer PRIMARY KEY,
+  created_at tmestamp NOT NULL
+);
+
+-- mig:assert column event_log.created_at type timestamp
+`)
+	err := RunWithFS(ctx, db, fs, options)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "assertion failed (line 6: column event_log.created_at type timestamp)")
+
+	// The file is rolled back and recorded as failed
+	var count int
+	require.NoError(t, db.GetContext(ctx, &count, "select count(*) from sqlite_schema where name='event_log'"))
+	require.Equal(t, 0, count)
+
+	var status Migration
+	require.NoError(t, db.GetContext(ctx, &status, "select * from migrations where filename='2-event-log.up.sql'"))
+	require.Equal(t, -1, status.StatementIndex)
+	require.Contains(t, status.Status, "assertion failed")
+
+	// Fixing the file applies it on the next run
+	fs["2-event-log.up.sql"] = []byte(`CREATE TABLE event_log (
+  id integer PRIMARY KEY,
+  created_at timestamp NOT NULL
+);
+
+-- mig:assert column event_log.created_at type timestamp
+`)
+	require.NoError(t, RunWithFS(ctx, db, fs, options))
+}
+
+func TestAssertionsInvalid(t *testing.T) {
+	testcases := []string{
+		"-- mig:assert table event",
+		"-- mig:assert view event exists",
+		"-- mig:assert column event exists",
+		"-- mig:assert column event.status type",
+		"-- mig:assert index idx_event unknown",
+	}
+
+	for _, tc := range testcases {
+		_, err := assertions(parse([]byte(tc)).directives)
+		require.Error(t, err, tc)
+	}
+}
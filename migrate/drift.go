@@ -0,0 +1,77 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/go-bridget/mig/model"
+)
+
+// Drift compares the schema of a database with the schema described by
+// its migrations. The migration files applied to sqldb are applied to an
+// empty scratch database, and both schemas are compared. In the result,
+// added tables, columns and indexes only exist in sqldb, e.g. after
+// manual hotfixes, and removed ones are missing from sqldb.
+//
+// Pending migration files are not applied to the scratch database, so
+// they aren't reported as drift. Of partially applied files, e.g. files
+// that failed on a driver without transactional DDL, the statements
+// recorded as applied are applied to the scratch database.
+func Drift(ctx context.Context, sqldb *sqlx.DB, scratch *sqlx.DB, fs FS, options *Options) (*model.Diff, error) {
+	files, err := Status(ctx, sqldb, fs, options)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := fs.Hooks()
+	for _, file := range files {
+		switch {
+		case file.State == StateApplied:
+			applied[file.Filename] = fs[file.Filename]
+		case file.Applied > 0:
+			filename, contents, err := appliedStatements(fs, file, driverName(sqldb))
+			if err != nil {
+				return nil, err
+			}
+			applied[filename] = contents
+		}
+	}
+
+	if err := RunWithFS(ctx, scratch, applied, options); err != nil {
+		return nil, fmt.Errorf("error applying migrations to scratch database: %w", err)
+	}
+
+	reference, err := snapshot(ctx, scratch)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := snapshot(ctx, sqldb)
+	if err != nil {
+		return nil, err
+	}
+	return model.Compare(reference, actual), nil
+}
+
+// appliedStatements returns the statements of a partially applied file
+// that are recorded as applied, as an SQL migration file.
+func appliedStatements(fs FS, file *FileStatus, driver string) (string, []byte, error) {
+	contents, err := fs.readFor(file.Filename, driver)
+	if err != nil {
+		return "", nil, fmt.Errorf("Error reading %s: %w", file.Filename, err)
+	}
+
+	stmts := parse(contents).statements
+	var result bytes.Buffer
+	for _, stmt := range stmts[:min(file.Applied, len(stmts))] {
+		fmt.Fprintf(&result, "%s;\n\n", stmt.query)
+	}
+
+	// Portable migrations are rendered to SQL already.
+	filename := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename)) + ".sql"
+	return filename, result.Bytes(), nil
+}
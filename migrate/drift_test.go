@@ -0,0 +1,83 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrift(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	options := &Options{
+		Project: "test",
+		Apply:   true,
+	}
+
+	fs := FS{
+		"1-users.up.sql":  []byte("CREATE TABLE users (id integer, email text);"),
+		"2-events.up.sql": []byte("CREATE TABLE events (id integer);"),
+	}
+	require.NoError(t, RunWithFS(ctx, db, FS{"1-users.up.sql": fs["1-users.up.sql"]}, options))
+
+	// Pending files aren't drift
+	diff, err := Drift(ctx, db, newTestDB(t), fs, options)
+	require.NoError(t, err)
+	require.True(t, diff.Empty(), diff.String())
+
+	// Manual changes are
+	for _, query := range []string{
+		"ALTER TABLE users ADD COLUMN age integer",
+		"CREATE INDEX users_email ON users (email)",
+		"CREATE TABLE hotfix (id integer)",
+	} {
+		_, err := db.ExecContext(ctx, query)
+		require.NoError(t, err)
+	}
+
+	diff, err = Drift(ctx, db, newTestDB(t), fs, options)
+	require.NoError(t, err)
+	require.Equal(t, "+ table hotfix\n~ table users\n  + column age bigint\n  ~ column email: key \"\" -> MUL\n  + index users_email (email)\n", diff.String())
+}
+
+func TestDriftPartial(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	options := &Options{
+		Project: "test",
+		Apply:   true,
+	}
+
+	fs := FS{
+		"1-users.up.sql":  []byte("CREATE TABLE users (id integer);"),
+		"2-events.up.sql": []byte("CREATE TABLE events (id integer);\nCREATE TABLE sessions (id integer);"),
+		"3-tags.up.yaml":  []byte("- create_table:\n    name: tags\n    columns:\n      - name: id\n        type: integer\n- add_column:\n    table: tags\n    name: name\n    type: text\n"),
+	}
+	require.NoError(t, RunWithFS(ctx, db, FS{"1-users.up.sql": fs["1-users.up.sql"]}, options))
+
+	// Record files that failed after the first statement, like on a
+	// driver without transactional DDL.
+	for _, query := range []string{
+		"CREATE TABLE events (id integer)",
+		`CREATE TABLE "tags" ("id" INTEGER)`,
+		"INSERT INTO migrations (project, filename, statement_index, status) VALUES ('test', '2-events.up.sql', 0, 'no such table: sessions')",
+		"INSERT INTO migrations (project, filename, statement_index, status) VALUES ('test', '3-tags.up.yaml', 0, 'running')",
+	} {
+		_, err := db.ExecContext(ctx, query)
+		require.NoError(t, err)
+	}
+
+	// The applied statements aren't drift
+	diff, err := Drift(ctx, db, newTestDB(t), fs, options)
+	require.NoError(t, err)
+	require.True(t, diff.Empty(), diff.String())
+
+	// Statements recorded as not applied are drift
+	_, err = db.ExecContext(ctx, "CREATE TABLE sessions (id integer)")
+	require.NoError(t, err)
+
+	diff, err = Drift(ctx, db, newTestDB(t), fs, options)
+	require.NoError(t, err)
+	require.Equal(t, "+ table sessions\n", diff.String())
+}
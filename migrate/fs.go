@@ -3,10 +3,20 @@ package migrate
 import (
 	"os"
 	"sort"
+	"strings"
 
 	"path/filepath"
 )
 
+// Hook files run on the migration connection, outside of transactions.
+const (
+	// BeforeHook runs before the migrations, after InitSQL.
+	BeforeHook = "_before.sql"
+
+	// AfterHook runs after all the migrations have been applied.
+	AfterHook = "_after.sql"
+)
+
 // FS represents a mapping between filename => contents.
 type FS map[string][]byte
 
@@ -15,7 +25,8 @@ func NewFS() FS {
 	return make(FS)
 }
 
-// Migrations returns list of SQL files to execute.
+// Migrations returns list of SQL files to execute, and portable
+// migrations (`*.up.yaml`) which are rendered to SQL.
 func (fs FS) Migrations() []string {
 	result := []string{}
 	for filename, contents := range fs {
@@ -23,7 +34,11 @@ func (fs FS) Migrations() []string {
 		if len(contents) < 2 {
 			continue
 		}
-		if matched, _ := filepath.Match("*.up.sql", filename); matched {
+		ext := filepath.Ext(filename)
+		if ext != ".sql" && !isPortable(filename) {
+			continue
+		}
+		if strings.HasSuffix(filename, ".up"+ext) {
 			result = append(result, filename)
 		}
 	}
@@ -31,6 +46,18 @@ func (fs FS) Migrations() []string {
 	return result
 }
 
+// Hooks returns the hook files from fs, to include them
+// with a subset of the migrations.
+func (fs FS) Hooks() FS {
+	result := NewFS()
+	for _, filename := range []string{BeforeHook, AfterHook} {
+		if contents, ok := fs[filename]; ok {
+			result[filename] = contents
+		}
+	}
+	return result
+}
+
 // ReadFile returns decoded file contents from FS.
 func (fs FS) ReadFile(filename string) ([]byte, error) {
 	if val, ok := fs[filename]; ok {
@@ -38,3 +65,30 @@ func (fs FS) ReadFile(filename string) ([]byte, error) {
 	}
 	return nil, os.ErrNotExist
 }
+
+// readFor returns file contents from FS with the `-- mig:if` blocks
+// for driver, see forDriver. Portable migrations are rendered to SQL
+// for the driver, see renderPortable.
+func (fs FS) readFor(filename string, driver string) ([]byte, error) {
+	contents, err := fs.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if isPortable(filename) {
+		return renderPortable(contents, driver)
+	}
+	return forDriver(contents, driver)
+}
+
+// Down returns the name of the down migration for an up migration file,
+// e.g. `2024-01-01-users.down.sql` for `2024-01-01-users.up.sql`. Portable
+// migrations use SQL down migrations too. The boolean is false if the down migration doesn't exist.
+func (fs FS) Down(filename string) (string, bool) {
+	ext := filepath.Ext(filename)
+	if !strings.HasSuffix(filename, ".up"+ext) {
+		return "", false
+	}
+	down := strings.TrimSuffix(filename, ".up"+ext) + ".down.sql"
+	_, ok := fs[down]
+	return down, ok
+}
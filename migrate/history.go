@@ -0,0 +1,82 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// HistoryTable records the execution time of each applied statement.
+const HistoryTable = "migration_history"
+
+// errHistory is returned when a statement can't be recorded in the history table.
+var errHistory = errors.New("recording migration history failed")
+
+// IsBookkeeping returns true for the tables mig uses to track migrations.
+func IsBookkeeping(table string) bool {
+	return table == "migrations" || table == HistoryTable
+}
+
+// HistoryEntry is a statement recorded in the history table.
+type HistoryEntry struct {
+	Project  string `db:"project"`
+	Filename string `db:"filename"`
+
+	// StatementIndex is the index of the statement in the file.
+	StatementIndex int `db:"statement_index"`
+
+	// DurationMS is the execution time of the statement in milliseconds.
+	DurationMS float64 `db:"duration_ms"`
+
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Duration returns the execution time of the statement.
+func (h *HistoryEntry) Duration() time.Duration {
+	return time.Duration(h.DurationMS * float64(time.Millisecond))
+}
+
+// History returns the statements applied for a project, most recent
+// first. A limit of zero returns all of them. If the history table
+// doesn't exist yet, the result is empty.
+func History(ctx context.Context, sqldb *sqlx.DB, project string, limit int) ([]*HistoryEntry, error) {
+	result := []*HistoryEntry{}
+
+	exists, err := tableExists(ctx, sqldb, HistoryTable)
+	if err != nil || !exists {
+		return result, err
+	}
+
+	query := "select project, filename, statement_index, duration_ms, applied_at from " + HistoryTable +
+		" where project=? order by applied_at desc, filename desc, statement_index desc"
+	if limit > 0 {
+		query += fmt.Sprintf(" limit %d", limit)
+	}
+	if err := sqldb.SelectContext(ctx, &result, sqldb.Rebind(query), project); err != nil {
+		return nil, fmt.Errorf("error reading migration history: %w", err)
+	}
+	return result, nil
+}
+
+// recordHistory inserts the execution time of a statement within the transaction.
+// Each applied statement is an insert, which adds a write per statement to
+// migrations with many statements. The row is written with the statement,
+// so non-transactional files resumed after a failure keep the history of
+// the statements applied before it.
+func (r *runner) recordHistory(ctx context.Context, tx *sqlx.Tx, filename string, idx int, duration time.Duration) error {
+	entry := &HistoryEntry{
+		Project:        r.options.Project,
+		Filename:       filename,
+		StatementIndex: idx,
+		DurationMS:     float64(duration) / float64(time.Millisecond),
+		AppliedAt:      time.Now(),
+	}
+	query := "INSERT INTO " + HistoryTable + " (project, filename, statement_index, duration_ms, applied_at) VALUES (:project, :filename, :statement_index, :duration_ms, :applied_at)"
+	if _, err := tx.NamedExecContext(ctx, query, entry); err != nil {
+		return fmt.Errorf("%w: %w", errHistory, err)
+	}
+	return nil
+}
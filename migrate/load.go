@@ -1,6 +1,7 @@
 package migrate
 
 import (
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path"
@@ -20,7 +21,7 @@ func assertDir(location string) error {
 	return errors.Errorf("path is not a directory: '%s'", location)
 }
 
-// Load reads migrations from disk.
+// Load reads migrations from disk, see Options.Path and Options.GitRef.
 func Load(options *Options) error {
 	project := options.Project
 	if options.Filename != "" {
@@ -37,24 +38,46 @@ func Load(options *Options) error {
 		return nil
 	}
 
-	if err := assertDir(options.Path); err != nil {
+	fsys, err := openPath(options)
+	if err != nil {
 		return err
 	}
 
-	files, err := filepath.Glob(path.Join(options.Path, "*.sql"))
+	result, err := ReadFS(fsys, ".")
 	if err != nil {
 		return err
 	}
 
-	migrations[project] = NewFS()
-	for _, filename := range files {
-		base := filepath.Base(filename)
-		contents, err := ioutil.ReadFile(filename)
+	manifest, err := ReadManifest(fsys, ".")
+	if err != nil {
+		return err
+	}
+
+	migrations[project] = result
+	manifests[project] = manifest
+	return nil
+}
+
+// ReadFS reads the sql files and portable migrations from a directory in fsys.
+// It can be used to read migrations from an embed.FS, or any other fs.FS
+// implementation.
+func ReadFS(fsys fs.FS, dir string) (FS, error) {
+	files := []string{}
+	for _, pattern := range []string{"*.sql", "*.up.yaml", "*.up.yml"} {
+		matches, err := fs.Glob(fsys, path.Join(dir, pattern))
 		if err != nil {
-			return err
+			return nil, err
 		}
-		migrations[project][base] = contents
+		files = append(files, matches...)
 	}
 
-	return nil
+	result := NewFS()
+	for _, filename := range files {
+		contents, err := fs.ReadFile(fsys, filename)
+		if err != nil {
+			return nil, err
+		}
+		result[path.Base(filename)] = contents
+	}
+	return result, nil
 }
@@ -0,0 +1,245 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the name of the project manifest in a migrations directory.
+const ManifestFile = "project.yaml"
+
+// Manifest declares project metadata, read from `project.yaml`.
+type Manifest struct {
+	// Requires lists the projects that must be migrated before this project.
+	Requires []*Requirement `yaml:"requires"`
+}
+
+// Requirement declares a dependency on the migrations of another project.
+type Requirement struct {
+	// Project is the name of the required project.
+	Project string `yaml:"project"`
+
+	// Until is the last migration file of the required project that must
+	// be applied. If empty, all migrations of the project must be applied.
+	Until string `yaml:"until,omitempty"`
+}
+
+// String returns the requirement as `project` or `project@until`.
+func (r *Requirement) String() string {
+	if r.Until == "" {
+		return r.Project
+	}
+	return r.Project + "@" + r.Until
+}
+
+// manifests holds loaded project manifests
+var manifests map[string]*Manifest = map[string]*Manifest{}
+
+// LoadedManifest returns the manifest loaded for a project with Load.
+// Projects without a manifest return an empty manifest.
+func LoadedManifest(project string) *Manifest {
+	if manifest, ok := manifests[project]; ok {
+		return manifest
+	}
+	return &Manifest{}
+}
+
+// ReadManifest reads the project manifest from a directory in fsys.
+// If the manifest doesn't exist, an empty manifest is returned.
+func ReadManifest(fsys fs.FS, dir string) (*Manifest, error) {
+	filename := path.Join(dir, ManifestFile)
+	contents, err := fs.ReadFile(fsys, filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	if err := yaml.Unmarshal(contents, manifest); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", filename, err)
+	}
+	for _, req := range manifest.Requires {
+		if req.Project == "" {
+			return nil, fmt.Errorf("error reading %s: requirement without project", filename)
+		}
+	}
+	return manifest, nil
+}
+
+// LoadAll reads migrations from each subdirectory of options.Path,
+// using the directory name as the project name. It returns the loaded
+// projects, ordered so that required projects come first.
+func LoadAll(options *Options) ([]string, error) {
+	fsys, err := openPath(options)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	projects := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		project := entry.Name()
+		result, err := ReadFS(fsys, project)
+		if err != nil {
+			return nil, err
+		}
+		if len(result) == 0 {
+			continue
+		}
+
+		manifest, err := ReadManifest(fsys, project)
+		if err != nil {
+			return nil, err
+		}
+
+		migrations[project] = result
+		manifests[project] = manifest
+		projects = append(projects, project)
+	}
+
+	if len(projects) == 0 {
+		return nil, fmt.Errorf("no projects found in '%s'", options.Path)
+	}
+	return Order(projects)
+}
+
+// Order sorts projects so that every project comes after the projects
+// it requires, using the loaded manifests. Requirements on projects that
+// aren't listed don't affect the order. Projects without dependencies
+// between them keep alphabetical order. A dependency cycle is an error.
+func Order(projects []string) ([]string, error) {
+	listed := map[string]bool{}
+	for _, project := range projects {
+		listed[project] = true
+	}
+
+	// pending counts unsorted requirements, dependents maps a
+	// project to the projects that require it.
+	pending := map[string]int{}
+	dependents := map[string][]string{}
+	for project := range listed {
+		seen := map[string]bool{}
+		for _, req := range LoadedManifest(project).Requires {
+			if !listed[req.Project] || seen[req.Project] {
+				continue
+			}
+			seen[req.Project] = true
+			pending[project]++
+			dependents[req.Project] = append(dependents[req.Project], project)
+		}
+	}
+
+	ready := []string{}
+	for project := range listed {
+		if pending[project] == 0 {
+			ready = append(ready, project)
+		}
+	}
+
+	result := []string{}
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		project := ready[0]
+		ready = ready[1:]
+		result = append(result, project)
+
+		for _, dependent := range dependents[project] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(result) < len(listed) {
+		cycle := []string{}
+		for project := range listed {
+			if pending[project] > 0 {
+				cycle = append(cycle, project)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, fmt.Errorf("dependency cycle between projects: %s", strings.Join(cycle, ", "))
+	}
+	return result, nil
+}
+
+// CheckRequirements checks that the projects required by the project in
+// options have been migrated far enough. If a required project has been
+// loaded, its files up to the required file must be fully applied. Otherwise,
+// the required file, or all recorded files of the project, must be recorded
+// as applied in the database.
+func CheckRequirements(ctx context.Context, sqldb *sqlx.DB, options *Options) error {
+	for _, req := range LoadedManifest(options.Project).Requires {
+		if err := checkRequirement(ctx, sqldb, req); err != nil {
+			return fmt.Errorf("project %s requires %s: %w", options.Project, req, err)
+		}
+	}
+	return nil
+}
+
+func checkRequirement(ctx context.Context, sqldb *sqlx.DB, req *Requirement) error {
+	if fs, ok := migrations[req.Project]; ok {
+		if _, ok := fs[req.Until]; req.Until != "" && !ok {
+			return fmt.Errorf("migration %s doesn't exist", req.Until)
+		}
+
+		files, err := Status(ctx, sqldb, fs, &Options{Project: req.Project})
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if req.Until != "" && file.Filename > req.Until {
+				break
+			}
+			if file.State != StateApplied {
+				return fmt.Errorf("migration %s is %s", file.Filename, file.State)
+			}
+		}
+		return nil
+	}
+
+	records, err := listMigrations(ctx, sqldb, req.Project)
+	if err != nil {
+		return err
+	}
+
+	if req.Until != "" {
+		record, ok := records[req.Until]
+		switch {
+		case !ok:
+			return fmt.Errorf("migration %s isn't applied", req.Until)
+		case record.Status != StatusOK:
+			return fmt.Errorf("migration %s isn't fully applied", req.Until)
+		}
+		return nil
+	}
+
+	if len(records) == 0 {
+		return errors.New("no migrations are applied")
+	}
+	for filename, record := range records {
+		if record.Status != StatusOK {
+			return fmt.Errorf("migration %s isn't fully applied", filename)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"billing/" + ManifestFile: {Data: []byte("requires:\n  - project: accounts\n    until: 1-accounts.up.sql\n  - project: audit\n")},
+		"invalid/" + ManifestFile: {Data: []byte("requires:\n  - until: 1-accounts.up.sql\n")},
+	}
+
+	manifest, err := ReadManifest(fsys, "billing")
+	require.NoError(t, err)
+	require.Len(t, manifest.Requires, 2)
+	require.Equal(t, "accounts@1-accounts.up.sql", manifest.Requires[0].String())
+	require.Equal(t, "audit", manifest.Requires[1].String())
+
+	manifest, err = ReadManifest(fsys, "accounts")
+	require.NoError(t, err)
+	require.Empty(t, manifest.Requires)
+
+	_, err = ReadManifest(fsys, "invalid")
+	require.ErrorContains(t, err, "error reading invalid/project.yaml: requirement without project")
+}
+
+func TestOrder(t *testing.T) {
+	manifests["order-billing"] = &Manifest{
+		Requires: []*Requirement{{Project: "order-zz-accounts", Until: "1-accounts.up.sql"}, {Project: "order-external"}},
+	}
+	manifests["order-reports"] = &Manifest{
+		Requires: []*Requirement{{Project: "order-billing"}, {Project: "order-zz-accounts"}},
+	}
+	t.Cleanup(func() {
+		delete(manifests, "order-billing")
+		delete(manifests, "order-reports")
+		delete(manifests, "order-zz-accounts")
+	})
+
+	// Requirements on projects that aren't listed don't affect the order
+	projects, err := Order([]string{"order-reports", "order-zz-accounts", "order-billing", "order-audit"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"order-audit", "order-zz-accounts", "order-billing", "order-reports"}, projects)
+	require.Equal(t, "order-zz-accounts@1-accounts.up.sql", LoadedManifest("order-billing").Requires[0].String())
+
+	manifests["order-zz-accounts"] = &Manifest{
+		Requires: []*Requirement{{Project: "order-reports"}},
+	}
+	_, err = Order([]string{"order-reports", "order-zz-accounts", "order-billing", "order-audit"})
+	require.ErrorContains(t, err, "dependency cycle between projects: order-billing, order-reports, order-zz-accounts")
+}
+
+func TestLoadAll(t *testing.T) {
+	dir := t.TempDir()
+	write := func(filename, contents string) {
+		filename = filepath.Join(dir, filename)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+		require.NoError(t, os.WriteFile(filename, []byte(contents), 0644))
+	}
+
+	write("zz-accounts/1-accounts.up.sql", "CREATE TABLE accounts (id integer);")
+	write("billing/1-invoices.up.sql", "CREATE TABLE invoices (account_id integer);")
+	write("billing/"+ManifestFile, "requires:\n  - project: zz-accounts\n    until: 1-accounts.up.sql\n")
+	write("audit/1-audit.up.sql", "CREATE TABLE audit (id integer);")
+	write("empty/README.md", "not a project")
+
+	projects, err := LoadAll(&Options{Path: dir})
+	require.NoError(t, err)
+	require.Equal(t, []string{"audit", "zz-accounts", "billing"}, projects)
+	require.Equal(t, "zz-accounts@1-accounts.up.sql", LoadedManifest("billing").Requires[0].String())
+
+	write("zz-accounts/"+ManifestFile, "requires:\n  - project: billing\n")
+	_, err = LoadAll(&Options{Path: dir})
+	require.ErrorContains(t, err, "dependency cycle between projects: billing, zz-accounts")
+}
+
+func TestCheckRequirements(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	accounts := FS{
+		"1-accounts.up.sql": []byte("CREATE TABLE accounts (id integer);"),
+		"2-profiles.up.sql": []byte("CREATE TABLE profiles (id integer);"),
+	}
+	migrations["req-billing"] = FS{
+		"1-invoices.up.sql": []byte("CREATE TABLE invoices (account_id integer);"),
+	}
+	manifests["req-billing"] = &Manifest{
+		Requires: []*Requirement{{Project: "req-accounts", Until: "1-accounts.up.sql"}},
+	}
+	t.Cleanup(func() {
+		delete(migrations, "req-billing")
+		delete(migrations, "req-accounts")
+		delete(manifests, "req-billing")
+	})
+
+	options := &Options{Project: "req-billing", Apply: true}
+
+	err := RunWithDB(ctx, db, options)
+	require.ErrorContains(t, err, "project req-billing requires req-accounts@1-accounts.up.sql: migration 1-accounts.up.sql isn't applied")
+
+	require.NoError(t, RunWithFS(ctx, db, FS{"1-accounts.up.sql": accounts["1-accounts.up.sql"]}, &Options{Project: "req-accounts", Apply: true}))
+	require.NoError(t, CheckRequirements(ctx, db, options))
+
+	// Loaded projects are checked against their files
+	migrations["req-accounts"] = accounts
+	require.NoError(t, CheckRequirements(ctx, db, options))
+
+	manifests["req-billing"].Requires[0].Until = ""
+	require.ErrorContains(t, CheckRequirements(ctx, db, options), "migration 2-profiles.up.sql is pending")
+
+	manifests["req-billing"].Requires[0].Until = "3-missing.up.sql"
+	require.ErrorContains(t, CheckRequirements(ctx, db, options), "migration 3-missing.up.sql doesn't exist")
+
+	manifests["req-billing"].Requires[0].Until = "1-accounts.up.sql"
+	require.NoError(t, RunWithDB(ctx, db, options))
+}
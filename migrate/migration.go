@@ -1,5 +1,10 @@
 package migrate
 
+import (
+	"errors"
+	"fmt"
+)
+
 // Migration type for database migrations.
 type (
 	// Migration holds the DB structure for the migration table.
@@ -25,3 +30,30 @@ var MigrationFields = []string{"project", "filename", "statement_index", "status
 
 // migrations holds loaded migrations
 var migrations map[string]FS = map[string]FS{}
+
+// Loaded returns the migrations loaded for a project with Load.
+func Loaded(project string) (FS, error) {
+	fs, ok := migrations[project]
+	if !ok {
+		return nil, fmt.Errorf("Migrations for '%s' don't exist", project)
+	}
+	return fs, nil
+}
+
+// Migration status values. Any other status holds the error message
+// of the statement that failed.
+const (
+	// StatusOK marks a migration file as fully applied.
+	StatusOK = "ok"
+
+	// StatusRunning marks a migration file as being applied. It's
+	// recorded for drivers without transactional DDL, where a crash or
+	// an interrupted run leaves the file partially applied, and when a
+	// run is stopped gracefully after some statements of the file.
+	StatusRunning = "running"
+)
+
+// ErrStopped is returned when migrations stop after a graceful stop
+// has been requested with Options.Stop. Running the migrations again
+// resumes with the next statement.
+var ErrStopped = errors.New("migrations stopped")
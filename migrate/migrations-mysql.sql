@@ -5,3 +5,12 @@ CREATE TABLE IF NOT EXISTS `migrations` (
  `status` text NOT NULL COMMENT 'ok or full error message',
  PRIMARY KEY (`project`,`filename`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Migration log of applied migrations';
+
+CREATE TABLE IF NOT EXISTS `migration_history` (
+ `project` varchar(16) NOT NULL COMMENT 'Microservice or project name',
+ `filename` varchar(255) NOT NULL COMMENT 'yyyy-mm-dd-HHMMSS.sql',
+ `statement_index` int(11) NOT NULL COMMENT 'Statement number from SQL file',
+ `duration_ms` double NOT NULL COMMENT 'Statement execution time in milliseconds',
+ `applied_at` datetime(6) NOT NULL COMMENT 'Time the statement was applied',
+ KEY `project_applied_at` (`project`,`applied_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Execution times of applied statements';
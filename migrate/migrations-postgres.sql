@@ -11,3 +11,20 @@ COMMENT ON COLUMN migrations.project IS 'Microservice or project name';
 COMMENT ON COLUMN migrations.filename IS 'yyyy-mm-dd-HHMMSS.sql';
 COMMENT ON COLUMN migrations.statement_index IS 'Statement number from SQL file';
 COMMENT ON COLUMN migrations.status IS 'ok or full error message';
+
+CREATE TABLE IF NOT EXISTS migration_history (
+    project varchar(16) NOT NULL,
+    filename varchar(255) NOT NULL,
+    statement_index int NOT NULL,
+    duration_ms double precision NOT NULL,
+    applied_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS migration_history_project_applied_at ON migration_history (project, applied_at);
+
+COMMENT ON TABLE migration_history IS 'Execution times of applied statements';
+COMMENT ON COLUMN migration_history.project IS 'Microservice or project name';
+COMMENT ON COLUMN migration_history.filename IS 'yyyy-mm-dd-HHMMSS.sql';
+COMMENT ON COLUMN migration_history.statement_index IS 'Statement number from SQL file';
+COMMENT ON COLUMN migration_history.duration_ms IS 'Statement execution time in milliseconds';
+COMMENT ON COLUMN migration_history.applied_at IS 'Time the statement was applied';
@@ -5,3 +5,13 @@ CREATE TABLE IF NOT EXISTS `migrations` (
  `status` text,
  PRIMARY KEY (project, filename)
 );
+
+CREATE TABLE IF NOT EXISTS `migration_history` (
+ `project` text,
+ `filename` text,
+ `statement_index` integer,
+ `duration_ms` real,
+ `applied_at` datetime
+);
+
+CREATE INDEX IF NOT EXISTS migration_history_project_applied_at ON migration_history (project, applied_at);
@@ -0,0 +1,186 @@
+// Package migtest applies migrations to in-memory SQLite databases
+// in Go tests, and compares the resulting schema with golden files.
+package migtest
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/go-bridget/mig/db/introspect"
+	"github.com/go-bridget/mig/migrate"
+	"github.com/go-bridget/mig/model"
+)
+
+// Project is the project name used for the migrations table.
+const Project = "migtest"
+
+var update = flag.Bool("migtest.update", false, "Update migtest golden files")
+
+// Open returns an empty in-memory SQLite database. The database
+// is closed when the test and all its subtests complete.
+func Open(tb testing.TB) *sqlx.DB {
+	tb.Helper()
+
+	handle, err := sql.Open("sqlite", ":memory:")
+	require.NoError(tb, err)
+	tb.Cleanup(func() {
+		handle.Close()
+	})
+
+	// keep a single connection, each :memory: connection is a new database
+	handle.SetMaxOpenConns(1)
+
+	return sqlx.NewDb(handle, "sqlite")
+}
+
+// New returns an in-memory SQLite database with all migrations from fs applied.
+func New(tb testing.TB, fs migrate.FS) *sqlx.DB {
+	tb.Helper()
+
+	db := Open(tb)
+	Apply(tb, db, fs)
+	return db
+}
+
+// Apply applies all the migrations from fs to the database.
+func Apply(tb testing.TB, db *sqlx.DB, fs migrate.FS) {
+	tb.Helper()
+
+	err := migrate.RunWithFS(context.Background(), db, fs, &migrate.Options{
+		Project: Project,
+		Apply:   true,
+	})
+	require.NoError(tb, err)
+}
+
+// Schema returns the introspected schema of the database,
+// excluding the migration bookkeeping tables.
+func Schema(tb testing.TB, db *sqlx.DB) []*model.Table {
+	tb.Helper()
+
+	ctx := context.Background()
+	describer, err := introspect.NewDescriber(db)
+	require.NoError(tb, err)
+
+	tables, err := introspect.ListTablesWithColumns(ctx, db, describer)
+	require.NoError(tb, err)
+
+	return slices.DeleteFunc(tables, func(table *model.Table) bool {
+		return migrate.IsBookkeeping(table.Name)
+	})
+}
+
+// Snapshot compares the introspected database schema with the golden YAML
+// file. Run tests with `-migtest.update` to write the golden file.
+func Snapshot(tb testing.TB, db *sqlx.DB, golden string) {
+	tb.Helper()
+
+	got, err := yaml.Marshal(Schema(tb, db))
+	require.NoError(tb, err)
+
+	if *update {
+		require.NoError(tb, os.MkdirAll(filepath.Dir(golden), 0755))
+		require.NoError(tb, os.WriteFile(golden, got, 0644))
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	require.NoError(tb, err, "golden file missing, run tests with -migtest.update")
+	require.Equal(tb, string(want), string(got), "schema doesn't match %s, run tests with -migtest.update", golden)
+}
+
+// Stepper applies migration files one at a time, to test
+// data migrations between two versions of the schema.
+type Stepper struct {
+	tb testing.TB
+	db *sqlx.DB
+	fs migrate.FS
+
+	files   []string
+	applied int
+}
+
+// NewStepper returns a Stepper for an empty in-memory SQLite database.
+func NewStepper(tb testing.TB, fs migrate.FS) *Stepper {
+	return &Stepper{
+		tb:    tb,
+		db:    Open(tb),
+		fs:    fs,
+		files: fs.Migrations(),
+	}
+}
+
+// DB returns the database the migrations are applied to.
+func (s *Stepper) DB() *sqlx.DB {
+	return s.db
+}
+
+// Applied returns the migration files applied so far.
+func (s *Stepper) Applied() []string {
+	return s.files[:s.applied]
+}
+
+// Pending returns the migration files that haven't been applied yet.
+func (s *Stepper) Pending() []string {
+	return s.files[s.applied:]
+}
+
+// Next applies the next migration file and returns its name.
+// It fails the test if all migration files have been applied.
+func (s *Stepper) Next() string {
+	s.tb.Helper()
+
+	if s.applied >= len(s.files) {
+		s.tb.Fatalf("no pending migrations, all %d files applied", len(s.files))
+	}
+	s.step(s.applied + 1)
+	return s.files[s.applied-1]
+}
+
+// To applies the migration files up to and including filename.
+func (s *Stepper) To(filename string) {
+	s.tb.Helper()
+
+	idx := slices.Index(s.files, filename)
+	if idx < 0 {
+		s.tb.Fatalf("unknown migration file: %s", filename)
+	}
+	if idx < s.applied {
+		s.tb.Fatalf("migration file already applied: %s", filename)
+	}
+	s.step(idx + 1)
+}
+
+// All applies all pending migration files.
+func (s *Stepper) All() {
+	s.tb.Helper()
+
+	s.step(len(s.files))
+}
+
+// step applies the first n migration files.
+func (s *Stepper) step(n int) {
+	s.tb.Helper()
+
+	fs := migrate.NewFS()
+	for filename, contents := range s.fs {
+		fs[filename] = contents
+	}
+	for _, filename := range s.files[n:] {
+		delete(fs, filename)
+	}
+
+	Apply(s.tb, s.db, fs)
+	s.applied = n
+}
@@ -0,0 +1,45 @@
+package migtest_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-bridget/mig/migrate"
+	"github.com/go-bridget/mig/migrate/migtest"
+)
+
+func loadFS(t *testing.T) migrate.FS {
+	fs, err := migrate.ReadFS(os.DirFS("testdata"), "schema")
+	require.NoError(t, err)
+	require.Len(t, fs.Migrations(), 2)
+	return fs
+}
+
+func TestNew(t *testing.T) {
+	db := migtest.New(t, loadFS(t))
+
+	migtest.Snapshot(t, db, "testdata/schema.yaml")
+}
+
+func TestStepper(t *testing.T) {
+	fs := loadFS(t)
+	step := migtest.NewStepper(t, fs)
+
+	step.To("2024-01-01-000000-pulse.up.sql")
+	require.Len(t, step.Pending(), 1)
+
+	_, err := step.DB().Exec("INSERT INTO pulse_hosts (user_id, hostname, created_at) VALUES ('user', 'host.example.com', '2024-01-15 10:00:00')")
+	require.NoError(t, err)
+
+	require.Equal(t, "2024-02-01-000000-pulse-hosts-name.up.sql", step.Next())
+	require.Empty(t, step.Pending())
+	require.Len(t, step.Applied(), 2)
+
+	var name string
+	require.NoError(t, step.DB().Get(&name, "SELECT name FROM pulse_hosts WHERE user_id='user'"))
+	require.Equal(t, "host.example.com", name)
+
+	migtest.Snapshot(t, step.DB(), "testdata/schema.yaml")
+}
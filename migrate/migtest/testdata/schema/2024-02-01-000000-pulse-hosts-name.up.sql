@@ -0,0 +1,3 @@
+ALTER TABLE pulse_hosts ADD COLUMN name TEXT NOT NULL DEFAULT '';
+
+UPDATE pulse_hosts SET name = hostname WHERE name = '';
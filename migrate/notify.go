@@ -0,0 +1,178 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+// Summary states of a migration run, database or project.
+const (
+	SummaryOK      = "ok"
+	SummaryFailed  = "failed"
+	SummaryStopped = "stopped"
+	SummarySkipped = "skipped"
+)
+
+// Summary describes a migration run. It's sent as JSON to notification
+// webhooks and commands when the run completes or fails.
+type Summary struct {
+	// Status is one of the Summary* states, failed if any database failed.
+	Status string `json:"status"`
+
+	Started    time.Time `json:"started"`
+	DurationMS int64     `json:"duration_ms"`
+
+	// Error is set if the run failed before migrating the databases.
+	Error string `json:"error,omitempty"`
+
+	Databases []*DatabaseSummary `json:"databases"`
+}
+
+// DatabaseSummary describes the migrations applied to a database.
+type DatabaseSummary struct {
+	// Database is the database name, or the DSN with the password redacted.
+	Database string `json:"database"`
+
+	Status     string `json:"status"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+
+	Projects []*ProjectSummary `json:"projects"`
+}
+
+// ProjectSummary describes the migrations applied for a project.
+type ProjectSummary struct {
+	Project string `json:"project"`
+	Status  string `json:"status"`
+
+	// Files lists the migration files applied.
+	Files []string `json:"files"`
+
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// NotifyOptions configures notifications sent after a migration run.
+type NotifyOptions struct {
+	// URLs receive the summary as a JSON POST request.
+	URLs []string
+
+	// Commands are run with `sh -c`, with the JSON summary on stdin.
+	Commands []string
+
+	// Timeout limits the time for each notification.
+	Timeout time.Duration
+}
+
+// NewNotifyOptions creates a new NotifyOptions instance with default values.
+func NewNotifyOptions() *NotifyOptions {
+	return &NotifyOptions{
+		Timeout: 10 * time.Second,
+	}
+}
+
+// Bind registers notification flags on the given FlagSet.
+func (options *NotifyOptions) Bind(fs *flag.FlagSet) {
+	fs.StringArrayVar(&options.URLs, "notify-url", options.URLs, "Webhook URL to post the JSON run summary to, may be repeated")
+	fs.StringArrayVar(&options.Commands, "notify-command", options.Commands, "Command to run with the JSON run summary on stdin, may be repeated")
+	fs.DurationVar(&options.Timeout, "notify-timeout", options.Timeout, "Timeout for each notification")
+}
+
+// Enabled returns true if any webhooks or commands are configured.
+func (options *NotifyOptions) Enabled() bool {
+	return len(options.URLs) > 0 || len(options.Commands) > 0
+}
+
+// Notify sends the summary to each webhook and command. All of them are
+// notified, and the errors are returned together.
+func Notify(ctx context.Context, options *NotifyOptions, summary *Summary) error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, target := range options.URLs {
+		if err := notifyURL(ctx, options.Timeout, target, body); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", redactURL(target), err))
+		}
+	}
+	for _, command := range options.Commands {
+		if err := notifyCommand(ctx, options.Timeout, command, body); err != nil {
+			errs = append(errs, fmt.Errorf("command %q: %w", command, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func notifyURL(ctx context.Context, timeout time.Duration, target string, body []byte) error {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// The error includes the URL, which may hold a token.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+	return nil
+}
+
+func notifyCommand(ctx context.Context, timeout time.Duration, command string, body []byte) error {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if out := strings.TrimSpace(output.String()); out != "" {
+			return fmt.Errorf("%w: %s", err, out)
+		}
+		return err
+	}
+	return nil
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// redactURL returns the scheme and host of a webhook URL. The path
+// and query are left out, as they often hold a secret token.
+func redactURL(target string) string {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return "(invalid url)"
+	}
+	return u.Scheme + "://" + u.Host + "/..."
+}
@@ -0,0 +1,99 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotify(t *testing.T) {
+	ctx := context.Background()
+
+	summary := &Summary{
+		Status:     SummaryFailed,
+		Started:    time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC),
+		DurationMS: 1500,
+		Databases: []*DatabaseSummary{{
+			Database:   "mig:xxxxx@tcp(db:3306)/app",
+			Status:     SummaryFailed,
+			DurationMS: 1500,
+			Error:      "1-users.up.sql statement 0 failed",
+			Projects: []*ProjectSummary{{
+				Project:    "app",
+				Status:     SummaryFailed,
+				Files:      []string{},
+				DurationMS: 1400,
+				Error:      "1-users.up.sql statement 0 failed",
+			}},
+		}},
+	}
+
+	var received []*http.Request
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r)
+		bodies = append(bodies, body)
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	output := filepath.Join(t.TempDir(), "summary.json")
+	options := &NotifyOptions{
+		URLs:     []string{server.URL + "/hook", server.URL + "/fail?token=secret"},
+		Commands: []string{"cat > " + output, "echo broken >&2; exit 3"},
+		Timeout:  5 * time.Second,
+	}
+	require.True(t, options.Enabled())
+
+	err := Notify(ctx, options, summary)
+	require.ErrorContains(t, err, "webhook "+server.URL+"/...: unexpected response: 502 Bad Gateway")
+	require.ErrorContains(t, err, `command "echo broken >&2; exit 3": exit status 3: broken`)
+	require.NotContains(t, err.Error(), "secret")
+
+	require.Len(t, received, 2)
+	require.Equal(t, http.MethodPost, received[0].Method)
+	require.Equal(t, "application/json", received[0].Header.Get("Content-Type"))
+
+	got := &Summary{}
+	require.NoError(t, json.Unmarshal(bodies[0], got))
+	require.Equal(t, summary, got)
+
+	contents, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"status": "failed",
+		"started": "2024-01-10T12:00:00Z",
+		"duration_ms": 1500,
+		"databases": [{
+			"database": "mig:xxxxx@tcp(db:3306)/app",
+			"status": "failed",
+			"duration_ms": 1500,
+			"error": "1-users.up.sql statement 0 failed",
+			"projects": [{
+				"project": "app",
+				"status": "failed",
+				"files": [],
+				"duration_ms": 1400,
+				"error": "1-users.up.sql statement 0 failed"
+			}]
+		}]
+	}`, string(contents))
+
+	// Connection errors don't leak the webhook token
+	server.Close()
+	err = Notify(ctx, &NotifyOptions{URLs: []string{server.URL + "/hook?token=secret"}}, summary)
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "secret")
+}
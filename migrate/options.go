@@ -1,14 +1,24 @@
 package migrate
 
 import (
+	"log"
+	"time"
+
 	flag "github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Options include migration options.
 type Options struct {
-	// Path contains sql files with your projects migrations.
+	// Path contains sql files with your projects migrations. It may
+	// be a directory, or a .tar.gz or .zip archive.
 	Path string
 
+	// GitRef reads Path from a git revision (commit, tag or branch)
+	// of the repository holding it, instead of the working tree.
+	GitRef string
+
 	// Project contains the project name for tracking migrations.
 	Project string
 
@@ -21,20 +31,100 @@ type Options struct {
 
 	// Verbose will output more details about migration execution.
 	Verbose bool
+
+	// CheckSafety analyzes pending statements for risky operations
+	// before applying them. Errors prevent the migrations from running.
+	CheckSafety bool
+
+	// Retries is the number of times a statement or a migration file
+	// is retried after failing with a transient error (deadlock, lock
+	// wait timeout, serialization failure). Zero disables retries.
+	Retries int
+
+	// RetryDelay is the delay before the first retry. It's doubled
+	// for every following retry, up to RetryMaxDelay.
+	RetryDelay time.Duration
+
+	// RetryMaxDelay caps the backoff delay between retries.
+	RetryMaxDelay time.Duration
+
+	// StatementTimeout limits the execution time of a single statement.
+	// Zero keeps the database default. On MySQL, it only limits SELECT
+	// statements.
+	StatementTimeout time.Duration
+
+	// LockTimeout limits the time a statement waits to acquire locks,
+	// so a migration blocked behind a long-running query fails fast.
+	// Zero keeps the database default.
+	LockTimeout time.Duration
+
+	// Schema is the postgres schema of a schema target. It's included in
+	// the migration lock keys, so migrations for tenants in different
+	// schemas don't block each other. Leave it empty otherwise, to keep
+	// excluding runs that lock the files by project and filename.
+	Schema string
+
+	// InitSQL holds statements executed on the migration connection before
+	// the migrations, e.g. `SET ROLE migrator`. They run before the
+	// `_before.sql` hook file.
+	InitSQL []string
+
+	// Stop requests a graceful stop when closed. The statement being
+	// executed finishes, the progress is recorded, and the run returns
+	// ErrStopped. Cancel the context to abort the statement instead.
+	Stop <-chan struct{}
+
+	// Logger receives the progress of the migrations. If nil,
+	// the standard logger is used.
+	Logger *log.Logger
+
+	// Timings collects the execution time of each statement applied
+	// from the migration files, if set.
+	Timings *Timings
+
+	// TracerProvider and MeterProvider record spans and metrics for the
+	// run. If nil, the global OpenTelemetry providers are used.
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
+
+	// TraceStatements adds the statements to the statement spans, as the
+	// `db.statement` attribute. Statements may hold data, e.g. from seed
+	// migrations, so they aren't traced by default.
+	TraceStatements bool
+}
+
+// logger returns the logger for migration progress.
+func (options *Options) logger() *log.Logger {
+	if options.Logger != nil {
+		return options.Logger
+	}
+	return log.Default()
 }
 
 // NewOptions creates a new Options instance with default values.
 func NewOptions() *Options {
 	return &Options{
 		Path: "schema",
+
+		Retries:       3,
+		RetryDelay:    time.Second,
+		RetryMaxDelay: 30 * time.Second,
 	}
 }
 
 // Bind registers migration flags on the given FlagSet.
 func (options *Options) Bind(fs *flag.FlagSet) {
-	fs.StringVar(&options.Path, "path", options.Path, "Project path for database migrations")
+	fs.StringVar(&options.Path, "path", options.Path, "Project path for database migrations, a directory or a .tar.gz or .zip archive")
+	fs.StringVar(&options.GitRef, "git-ref", options.GitRef, "Read the project path from a git revision instead of the working tree")
 	fs.StringVar(&options.Project, "project", options.Project, "Project name for migrations (db key)")
 	fs.StringVarP(&options.Filename, "filename", "f", options.Filename, "Single file sql for migrations")
 	fs.BoolVar(&options.Apply, "apply", options.Apply, "false = print migrations, true = run migrations")
 	fs.BoolVar(&options.Verbose, "verbose", options.Verbose, "false = print summary, true = print details")
+	fs.BoolVar(&options.CheckSafety, "check-safety", options.CheckSafety, "Check pending statements for risky operations before applying")
+	fs.IntVar(&options.Retries, "retries", options.Retries, "Retries for transient errors (deadlocks, lock timeouts), 0 = disabled")
+	fs.DurationVar(&options.RetryDelay, "retry-delay", options.RetryDelay, "Delay before the first retry, doubled on each retry")
+	fs.DurationVar(&options.RetryMaxDelay, "retry-max-delay", options.RetryMaxDelay, "Maximum delay between retries")
+	fs.DurationVar(&options.StatementTimeout, "statement-timeout", options.StatementTimeout, "Maximum execution time for a statement, 0 = database default (MySQL: SELECT statements only)")
+	fs.StringArrayVar(&options.InitSQL, "init-sql", options.InitSQL, "Statement to run on the migration connection before migrations, may be repeated")
+	fs.DurationVar(&options.LockTimeout, "lock-timeout", options.LockTimeout, "Maximum wait time for locks, 0 = database default")
 }
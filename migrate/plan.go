@@ -0,0 +1,436 @@
+package migrate
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-bridget/mig/model"
+)
+
+// Plan returns the DDL statements that change a database schema as
+// described by diff, for the driver. Statements are returned without
+// the trailing `;`.
+//
+// Column types are rendered from the normalized column types (integer,
+// text, timestamp, date, decimal, boolean, blob) and enum values, and
+// identifiers are quoted for the driver. Nullability and defaults are
+// rendered when set, but aren't filled by introspection, and auto
+// increment isn't part of the schema model, so the generated statements
+// should be reviewed. Statements that lose them, like MODIFY COLUMN on
+// MySQL, start with a `-- review:` comment. Changes the driver can't
+// apply, like changing a column type on SQLite, are errors.
+func Plan(diff *model.Diff, driverName string) ([]string, error) {
+	p := &planner{driver: driverName}
+	if p.driver == "pgx" {
+		p.driver = "postgres"
+	}
+	switch p.driver {
+	case "mysql", "postgres", "sqlite":
+	default:
+		return nil, fmt.Errorf("unsupported driver: %s", driverName)
+	}
+
+	for _, table := range diff.Added {
+		p.createTable(table)
+	}
+	for _, table := range diff.Changed {
+		p.alterTable(table)
+	}
+	for _, table := range diff.Removed {
+		p.add("DROP TABLE %s", p.quote(table.Name))
+		for _, column := range table.Columns {
+			p.dropEnum(table, column)
+		}
+	}
+
+	if len(p.errs) > 0 {
+		return nil, fmt.Errorf("can't plan schema changes for %s:\n%s", p.driver, strings.Join(p.errs, "\n"))
+	}
+	return p.statements, nil
+}
+
+// planner collects statements and unsupported changes for Plan.
+type planner struct {
+	driver     string
+	statements []string
+	errs       []string
+}
+
+func (p *planner) add(format string, args ...interface{}) {
+	p.statements = append(p.statements, fmt.Sprintf(format, args...))
+}
+
+func (p *planner) unsupported(format string, args ...interface{}) {
+	p.errs = append(p.errs, "- "+fmt.Sprintf(format, args...))
+}
+
+func (p *planner) createTable(table *model.Table) {
+	lines := []string{}
+	for _, column := range table.Columns {
+		p.createEnum(table, column)
+		lines = append(lines, "\t"+p.columnDefinition(table, column))
+	}
+	for _, index := range table.Indexes {
+		if index.Primary {
+			lines = append(lines, fmt.Sprintf("\tPRIMARY KEY (%s)", p.quoteList(index.Columns)))
+		}
+	}
+
+	query := fmt.Sprintf("CREATE TABLE %s (\n%s\n)", p.quote(table.Name), strings.Join(lines, ",\n"))
+	if p.driver == "mysql" && hasComment(table.Comment, table.Title()) {
+		query += " COMMENT=" + quoteString(table.Comment)
+	}
+	p.statements = append(p.statements, query)
+
+	for _, index := range table.Indexes {
+		if !index.Primary {
+			p.createIndex(table.Name, index)
+		}
+	}
+
+	if p.driver == "postgres" {
+		p.tableComment(table)
+		for _, column := range table.Columns {
+			p.columnComment(table, column)
+		}
+	}
+}
+
+func (p *planner) alterTable(diff *model.TableDiff) {
+	table := diff.To
+
+	for _, index := range diff.RemovedIndexes {
+		p.dropIndex(table.Name, index)
+	}
+	for _, index := range diff.ChangedIndexes {
+		p.dropIndex(table.Name, index.From)
+	}
+
+	for _, column := range diff.AddedColumns {
+		p.createEnum(table, column)
+		p.add("ALTER TABLE %s ADD COLUMN %s", p.quote(table.Name), p.columnDefinition(table, column))
+		if p.driver == "postgres" {
+			p.columnComment(table, column)
+		}
+	}
+	for _, column := range diff.ChangedColumns {
+		p.alterColumn(table, column)
+	}
+	for _, column := range diff.RemovedColumns {
+		p.add("ALTER TABLE %s DROP COLUMN %s", p.quote(table.Name), p.quote(column.Name))
+		p.dropEnum(table, column)
+	}
+
+	for _, index := range diff.AddedIndexes {
+		p.createIndex(table.Name, index)
+	}
+	for _, index := range diff.ChangedIndexes {
+		p.createIndex(table.Name, index.To)
+	}
+
+	if slices.ContainsFunc(diff.Changes, isField("comment")) {
+		switch p.driver {
+		case "mysql":
+			p.add("ALTER TABLE %s COMMENT=%s", p.quote(table.Name), quoteString(table.Comment))
+		case "postgres":
+			p.tableComment(table)
+		}
+	}
+}
+
+func (p *planner) alterColumn(table *model.Table, diff *model.ColumnDiff) {
+	from, to := diff.From, diff.To
+
+	// Key changes follow from index changes, and don't change the column.
+	typeChanged := slices.ContainsFunc(diff.Changes, isField("type", "datatype", "size", "scale", "values"))
+	commentChanged := slices.ContainsFunc(diff.Changes, isField("comment"))
+
+	switch p.driver {
+	case "mysql":
+		// MODIFY COLUMN replaces the column definition, which only
+		// holds the type and comment from the schema model.
+		if typeChanged || commentChanged {
+			p.add("-- review: MODIFY COLUMN drops NOT NULL, DEFAULT and AUTO_INCREMENT, add them if %s.%s has them\n"+
+				"ALTER TABLE %s MODIFY COLUMN %s", table.Name, to.Name, p.quote(table.Name), p.columnDefinition(table, to))
+		}
+	case "postgres":
+		if typeChanged {
+			switch {
+			case len(from.Values) > 0 && len(to.Values) > 0:
+				p.alterEnum(table, from, to)
+			default:
+				p.createEnum(table, to)
+				columnType := p.columnType(table, to)
+				p.add("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s", p.quote(table.Name), p.quote(to.Name), columnType, p.quote(to.Name), columnType)
+				p.dropEnum(table, from)
+			}
+		}
+		if commentChanged {
+			p.columnComment(table, to)
+		}
+	case "sqlite":
+		if typeChanged {
+			p.unsupported("changing column %s.%s (%s) needs the table to be recreated", table.Name, to.Name, changes(diff.Changes))
+		}
+	}
+}
+
+// alterEnum adds values to a Postgres enum type. Values can't be removed.
+func (p *planner) alterEnum(table *model.Table, from, to *model.Column) {
+	for _, value := range from.Values {
+		if !slices.Contains(to.Values, value) {
+			p.unsupported("removing value %s from enum column %s.%s", quoteString(value), table.Name, to.Name)
+		}
+	}
+	for _, value := range to.Values {
+		if !slices.Contains(from.Values, value) {
+			p.add("ALTER TYPE %s ADD VALUE %s", p.quote(enumType(table, to)), quoteString(value))
+		}
+	}
+}
+
+// createEnum creates the Postgres enum type for an enum column.
+func (p *planner) createEnum(table *model.Table, column *model.Column) {
+	if p.driver != "postgres" || len(column.Values) == 0 {
+		return
+	}
+	p.add("CREATE TYPE %s AS ENUM (%s)", p.quote(enumType(table, column)), quoteValues(column.Values))
+}
+
+// dropEnum drops the Postgres enum type of a removed enum column.
+func (p *planner) dropEnum(table *model.Table, column *model.Column) {
+	if p.driver != "postgres" || len(column.Values) == 0 {
+		return
+	}
+	p.add("DROP TYPE %s", p.quote(enumType(table, column)))
+}
+
+func (p *planner) createIndex(tableName string, index *model.Index) {
+	columns := p.quoteList(index.Columns)
+	if index.Primary {
+		if p.driver == "sqlite" {
+			p.unsupported("adding a primary key to %s needs the table to be recreated", tableName)
+			return
+		}
+		p.add("ALTER TABLE %s ADD PRIMARY KEY (%s)", p.quote(tableName), columns)
+		return
+	}
+
+	name := index.Name
+	if name == "" {
+		name = "idx_" + tableName + "_" + strings.Join(index.Columns, "_")
+	}
+	unique := ""
+	if index.Unique {
+		unique = "UNIQUE "
+	}
+	p.add("CREATE %sINDEX %s ON %s (%s)", unique, p.quote(name), p.quote(tableName), columns)
+}
+
+func (p *planner) dropIndex(tableName string, index *model.Index) {
+	if index.Primary {
+		switch p.driver {
+		case "mysql":
+			p.add("ALTER TABLE %s DROP PRIMARY KEY", p.quote(tableName))
+		case "postgres":
+			name := index.Name
+			if name == "" {
+				name = tableName + "_pkey"
+			}
+			p.add("ALTER TABLE %s DROP CONSTRAINT %s", p.quote(tableName), p.quote(name))
+		default:
+			p.unsupported("removing the primary key of %s needs the table to be recreated", tableName)
+		}
+		return
+	}
+
+	if index.Name == "" {
+		p.unsupported("removing index %s from %s needs the index name", index, tableName)
+		return
+	}
+	if p.driver == "mysql" {
+		p.add("DROP INDEX %s ON %s", p.quote(index.Name), p.quote(tableName))
+		return
+	}
+	p.add("DROP INDEX %s", p.quote(index.Name))
+}
+
+func (p *planner) tableComment(table *model.Table) {
+	if hasComment(table.Comment, table.Title()) {
+		p.add("COMMENT ON TABLE %s IS %s", p.quote(table.Name), quoteString(table.Comment))
+	}
+}
+
+func (p *planner) columnComment(table *model.Table, column *model.Column) {
+	if hasComment(column.Comment, column.Title()) {
+		p.add("COMMENT ON COLUMN %s.%s IS %s", p.quote(table.Name), p.quote(column.Name), quoteString(column.Comment))
+	}
+}
+
+// columnDefinition returns the column name and type, with the nullability
+// and default if set, and the comment on MySQL.
+func (p *planner) columnDefinition(table *model.Table, column *model.Column) string {
+	result := p.quote(column.Name) + " " + p.columnType(table, column)
+	if column.Nullable != nil && !*column.Nullable {
+		result += " NOT NULL"
+	}
+	if column.Default != nil {
+		result += " DEFAULT " + defaultValue(*column.Default)
+	}
+	if p.driver == "mysql" && hasComment(column.Comment, column.Title()) {
+		result += " COMMENT " + quoteString(column.Comment)
+	}
+	return result
+}
+
+// columnType renders the column type for the driver.
+func (p *planner) columnType(table *model.Table, column *model.Column) string {
+	dataType := strings.ToLower(column.DataType)
+
+	if len(column.Values) > 0 {
+		switch p.driver {
+		case "mysql":
+			return "ENUM(" + quoteValues(column.Values) + ")"
+		case "postgres":
+			return p.quote(enumType(table, column))
+		default:
+			return "TEXT CHECK (" + p.quote(column.Name) + " IN (" + quoteValues(column.Values) + "))"
+		}
+	}
+
+	switch strings.ToLower(column.Type) {
+	case "integer":
+		switch {
+		case p.driver == "sqlite":
+			return "INTEGER"
+		case column.Size == 2 || dataType == "smallint":
+			return "SMALLINT"
+		case column.Size == 4 || dataType == "int":
+			return p.pick("INT", "INTEGER", "")
+		case p.driver == "mysql" && slices.Contains([]string{"tinyint", "mediumint"}, dataType):
+			return strings.ToUpper(dataType)
+		}
+		return "BIGINT"
+	case "text":
+		switch {
+		case p.driver == "sqlite":
+			return "TEXT"
+		case (dataType == "varchar" || dataType == "char") && column.Size > 0:
+			return fmt.Sprintf("%s(%d)", strings.ToUpper(dataType), column.Size)
+		case dataType == "json" || (dataType == "jsonb" && p.driver == "postgres"):
+			return strings.ToUpper(dataType)
+		}
+		return "TEXT"
+	case "timestamp":
+		if p.driver == "mysql" && dataType == "datetime" {
+			return "DATETIME"
+		}
+		if p.driver == "postgres" && dataType == "timestamptz" {
+			return "TIMESTAMPTZ"
+		}
+		return "TIMESTAMP"
+	case "date":
+		return "DATE"
+	case "decimal":
+		if slices.Contains([]string{"float", "float4", "float8", "double", "real"}, dataType) {
+			return p.pick("DOUBLE", "DOUBLE PRECISION", "REAL")
+		}
+		if column.Size > 0 {
+			return fmt.Sprintf("%s(%d,%d)", p.pick("DECIMAL", "NUMERIC", "NUMERIC"), column.Size, column.Scale)
+		}
+		// Without a precision, use the largest one on MySQL, which
+		// doesn't support unconstrained decimals.
+		return p.pick("DECIMAL(65,30)", "NUMERIC", "NUMERIC")
+	case "boolean":
+		return "BOOLEAN"
+	case "blob":
+		return p.pick("BLOB", "BYTEA", "BLOB")
+	case "":
+		return strings.ToUpper(column.DataType)
+	}
+	return strings.ToUpper(column.Type)
+}
+
+// pick returns the value for the driver. An empty sqlite value
+// falls back to the postgres value.
+func (p *planner) pick(mysql, postgres, sqlite string) string {
+	switch p.driver {
+	case "mysql":
+		return mysql
+	case "sqlite":
+		if sqlite != "" {
+			return sqlite
+		}
+	}
+	return postgres
+}
+
+// quote returns the quoted identifier for the driver.
+func (p *planner) quote(name string) string {
+	if p.driver == "mysql" {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteList returns the quoted identifiers separated by commas.
+func (p *planner) quoteList(names []string) string {
+	result := make([]string, len(names))
+	for i, name := range names {
+		result[i] = p.quote(name)
+	}
+	return strings.Join(result, ", ")
+}
+
+// enumType returns the Postgres type name for an enum column.
+func enumType(table *model.Table, column *model.Column) string {
+	return table.Name + "_" + column.Name
+}
+
+// defaultValue renders a column default. Numbers, booleans, NULL and
+// CURRENT_TIMESTAMP are kept as they are, other values are quoted.
+func defaultValue(value string) string {
+	switch strings.ToUpper(value) {
+	case "TRUE", "FALSE", "NULL", "CURRENT_TIMESTAMP":
+		return strings.ToUpper(value)
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	return quoteString(value)
+}
+
+// hasComment returns false for empty comments, and for comments filled
+// in with the title of the table or column by introspection.
+func hasComment(comment, title string) bool {
+	return comment != "" && comment != title
+}
+
+// isField returns a function matching changes for any of the fields.
+func isField(fields ...string) func(model.Change) bool {
+	return func(change model.Change) bool {
+		return slices.Contains(fields, change.Field)
+	}
+}
+
+func changes(list []model.Change) string {
+	result := make([]string, len(list))
+	for i, change := range list {
+		result[i] = change.String()
+	}
+	return strings.Join(result, ", ")
+}
+
+func quoteString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func quoteValues(values []string) string {
+	result := make([]string, len(values))
+	for i, value := range values {
+		result[i] = quoteString(value)
+	}
+	return strings.Join(result, ", ")
+}
@@ -0,0 +1,102 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-bridget/mig/model"
+)
+
+func TestPlan(t *testing.T) {
+	ctx := context.Background()
+
+	exec := func(db *sqlx.DB, stmts ...string) {
+		for _, stmt := range stmts {
+			_, err := db.ExecContext(ctx, stmt)
+			require.NoError(t, err, stmt)
+		}
+	}
+
+	// The desired schema is read from a database created with the target DDL
+	desiredDB := newTestDB(t)
+	exec(desiredDB,
+		"CREATE TABLE users (id INTEGER, email TEXT, status TEXT, PRIMARY KEY (id))",
+		"CREATE UNIQUE INDEX users_email ON users (email)",
+		"CREATE TABLE events (\n\tid INTEGER,\n\tuser_id INTEGER,\n\tkind TEXT CHECK (kind IN ('click', 'view')),\n\tcreated_at TIMESTAMP,\n\tPRIMARY KEY (id)\n)",
+		"CREATE INDEX events_user_id ON events (user_id)",
+	)
+	desired, err := snapshot(ctx, desiredDB)
+	require.NoError(t, err)
+
+	db := newTestDB(t)
+	exec(db,
+		"CREATE TABLE users (id INTEGER, email TEXT, name TEXT, PRIMARY KEY (id))",
+		"CREATE INDEX users_email ON users (email)",
+		"CREATE TABLE sessions (id INTEGER)",
+	)
+	current, err := snapshot(ctx, db)
+	require.NoError(t, err)
+
+	stmts, err := Plan(model.Compare(current, desired), "sqlite")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"CREATE TABLE \"events\" (\n\t\"id\" INTEGER,\n\t\"user_id\" INTEGER,\n\t\"kind\" TEXT CHECK (\"kind\" IN ('click', 'view')),\n\t\"created_at\" TIMESTAMP,\n\tPRIMARY KEY (\"id\")\n)",
+		`CREATE INDEX "events_user_id" ON "events" ("user_id")`,
+		`DROP INDEX "users_email"`,
+		`ALTER TABLE "users" ADD COLUMN "status" TEXT`,
+		`ALTER TABLE "users" DROP COLUMN "name"`,
+		`CREATE UNIQUE INDEX "users_email" ON "users" ("email")`,
+		`DROP TABLE "sessions"`,
+	}, stmts)
+
+	exec(db, stmts...)
+	planned, err := snapshot(ctx, db)
+	require.NoError(t, err)
+	require.True(t, model.Compare(planned, desired).Empty(), model.Compare(planned, desired).String())
+
+	// Changing a column type isn't supported on sqlite
+	require.Equal(t, "events", desired[0].Name)
+	require.Equal(t, "user_id", desired[0].Columns[1].Name)
+	desired[0].Columns[1].Type = "text"
+	_, err = Plan(model.Compare(planned, desired), "sqlite")
+	require.ErrorContains(t, err, "changing column")
+
+	stmts, err = Plan(model.Compare(planned, desired), "postgres")
+	require.NoError(t, err)
+	require.Contains(t, stmts, `ALTER TABLE "events" ALTER COLUMN "user_id" TYPE TEXT USING "user_id"::TEXT`)
+
+	// MODIFY COLUMN on mysql is marked for review
+	stmts, err = Plan(model.Compare(planned, desired), "mysql")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"-- review: MODIFY COLUMN drops NOT NULL, DEFAULT and AUTO_INCREMENT, add them if events.user_id has them\n" +
+			"ALTER TABLE `events` MODIFY COLUMN `user_id` TEXT",
+	}, stmts)
+
+	// Enum columns
+	stmts, err = Plan(model.Compare(nil, desired), "postgres")
+	require.NoError(t, err)
+	require.Equal(t, `CREATE TYPE "events_kind" AS ENUM ('click', 'view')`, stmts[0])
+	require.Contains(t, stmts[1], "\t\"kind\" \"events_kind\",\n")
+
+	stmts, err = Plan(model.Compare(nil, desired), "mysql")
+	require.NoError(t, err)
+	require.Contains(t, stmts[0], "\t`kind` ENUM('click', 'view'),\n")
+
+	// Enum types are dropped with the columns and tables
+	stmts, err = Plan(model.Compare(desired, nil), "postgres")
+	require.NoError(t, err)
+	require.Equal(t, []string{`DROP TABLE "events"`, `DROP TYPE "events_kind"`, `DROP TABLE "users"`}, stmts)
+
+	// Decimal columns keep the precision and scale
+	prices := []*model.Table{{
+		Name:    "prices",
+		Columns: []*model.Column{{Name: "amount", Type: "decimal", DataType: "decimal", Size: 10, Scale: 2}},
+	}}
+	stmts, err = Plan(model.Compare(nil, prices), "mysql")
+	require.NoError(t, err)
+	require.Equal(t, "CREATE TABLE `prices` (\n\t`amount` DECIMAL(10,2)\n)", stmts[0])
+}
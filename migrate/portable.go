@@ -0,0 +1,177 @@
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-bridget/mig/model"
+)
+
+// portableExtensions are the extensions of portable migration files,
+// which are rendered to SQL for the database driver. Portable migrations
+// are written in YAML, HCL isn't supported.
+var portableExtensions = []string{".yaml", ".yml"}
+
+// portableTypes are the column types of portable migrations, as
+// normalized by introspection. Enum columns set values instead.
+var portableTypes = []string{"integer", "text", "timestamp", "date", "decimal", "boolean", "blob"}
+
+// Operation is a step of a portable migration. One field is set.
+type Operation struct {
+	CreateTable *model.Table      `yaml:"create_table,omitempty"`
+	AddColumn   *AddColumn        `yaml:"add_column,omitempty"`
+	AddIndex    *AddIndex         `yaml:"add_index,omitempty"`
+	Comment     *CommentOperation `yaml:"comment,omitempty"`
+}
+
+// AddColumn adds a column to a table.
+type AddColumn struct {
+	Table        string `yaml:"table"`
+	model.Column `yaml:",inline"`
+}
+
+// AddIndex adds an index to a table.
+type AddIndex struct {
+	Table       string `yaml:"table"`
+	model.Index `yaml:",inline"`
+}
+
+// CommentOperation sets the comment of a table, or of a column if Column
+// is set. Column comments can't be changed on MySQL, where MODIFY COLUMN
+// needs the full column definition.
+type CommentOperation struct {
+	Table   string `yaml:"table"`
+	Column  string `yaml:"column,omitempty"`
+	Comment string `yaml:"comment"`
+}
+
+// isPortable returns true for portable migration files, e.g. `2024-01-01-users.up.yaml`.
+func isPortable(filename string) bool {
+	return slices.ContainsFunc(portableExtensions, func(ext string) bool {
+		return strings.HasSuffix(filename, ext)
+	})
+}
+
+// renderPortable renders a portable migration to SQL statements for
+// the driver, one statement per operation or more. Without a driver,
+// the statements are rendered for postgres.
+func renderPortable(contents []byte, driver string) ([]byte, error) {
+	if driver == "" {
+		driver = "postgres"
+	}
+
+	operations := []*Operation{}
+	if err := yaml.Unmarshal(contents, &operations); err != nil {
+		return nil, fmt.Errorf("error reading portable migration: %w", err)
+	}
+
+	var result bytes.Buffer
+	for idx, operation := range operations {
+		diff, err := operation.diff(driver)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", idx+1, err)
+		}
+		stmts, err := Plan(diff, driver)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", idx+1, err)
+		}
+		for _, stmt := range stmts {
+			fmt.Fprintf(&result, "%s;\n\n", stmt)
+		}
+	}
+	return result.Bytes(), nil
+}
+
+// diff returns the schema change of the operation, to render it with Plan.
+func (o *Operation) diff(driver string) (*model.Diff, error) {
+	switch {
+	case o.CreateTable != nil:
+		table := o.CreateTable
+		if table.Name == "" {
+			return nil, fmt.Errorf("create_table is missing a name")
+		}
+		if len(table.Columns) == 0 {
+			return nil, fmt.Errorf("create_table %s has no columns", table.Name)
+		}
+		for _, column := range table.Columns {
+			if err := portableColumn(table.Name, column); err != nil {
+				return nil, err
+			}
+		}
+		return &model.Diff{Added: []*model.Table{table}}, nil
+
+	case o.AddColumn != nil:
+		column := &o.AddColumn.Column
+		if err := portableColumn(o.AddColumn.Table, column); err != nil {
+			return nil, err
+		}
+		return changeTable(&model.Table{Name: o.AddColumn.Table}, &model.TableDiff{
+			AddedColumns: []*model.Column{column},
+		})
+
+	case o.AddIndex != nil:
+		index := &o.AddIndex.Index
+		if len(index.Columns) == 0 {
+			return nil, fmt.Errorf("add_index on %s has no columns", o.AddIndex.Table)
+		}
+		return changeTable(&model.Table{Name: o.AddIndex.Table}, &model.TableDiff{
+			AddedIndexes: []*model.Index{index},
+		})
+
+	case o.Comment != nil:
+		comment := o.Comment
+		change := model.Change{Field: "comment", To: comment.Comment}
+		if comment.Column == "" {
+			return changeTable(&model.Table{Name: comment.Table, Comment: comment.Comment}, &model.TableDiff{
+				Changes: []model.Change{change},
+			})
+		}
+
+		if driver == "mysql" {
+			return nil, fmt.Errorf("comment on %s.%s isn't supported on mysql, use an SQL migration with the full column definition", comment.Table, comment.Column)
+		}
+		column := &model.Column{Name: comment.Column, Comment: comment.Comment}
+		return changeTable(&model.Table{Name: comment.Table}, &model.TableDiff{
+			ChangedColumns: []*model.ColumnDiff{{
+				Name:    column.Name,
+				From:    column,
+				To:      column,
+				Changes: []model.Change{change},
+			}},
+		})
+	}
+	return nil, fmt.Errorf("unknown operation, expected one of create_table, add_column, add_index, comment")
+}
+
+// changeTable returns a diff changing table to the table passed.
+func changeTable(table *model.Table, diff *model.TableDiff) (*model.Diff, error) {
+	if table.Name == "" {
+		return nil, fmt.Errorf("operation is missing a table")
+	}
+	diff.Name, diff.From, diff.To = table.Name, &model.Table{Name: table.Name}, table
+	return &model.Diff{Changed: []*model.TableDiff{diff}}, nil
+}
+
+// portableColumn checks the column type. Sized text columns are varchar.
+func portableColumn(table string, column *model.Column) error {
+	if column.Name == "" {
+		return fmt.Errorf("column in %s is missing a name", table)
+	}
+	if len(column.Values) > 0 {
+		if column.Type != "" && column.Type != "enum" {
+			return fmt.Errorf("enum column %s.%s has type %s", table, column.Name, column.Type)
+		}
+		return nil
+	}
+	if !slices.Contains(portableTypes, column.Type) {
+		return fmt.Errorf("column %s.%s has type %q, expected one of %s or values for an enum", table, column.Name, column.Type, strings.Join(portableTypes, ", "))
+	}
+	if column.Type == "text" && column.Size > 0 && column.DataType == "" {
+		column.DataType = "varchar"
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package migrate
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var portableUsers = []byte(`
+- create_table:
+    name: users
+    comment: Registered users
+    columns:
+      - name: id
+        type: integer
+        nullable: false
+      - name: email
+        type: text
+        size: 255
+        nullable: false
+        comment: Login email
+      - name: status
+        values: [active, disabled]
+        default: active
+    indexes:
+      - primary: true
+        columns: [id]
+      - unique: true
+        columns: [email]
+- add_column:
+    table: users
+    name: created_at
+    type: timestamp
+- add_column:
+    table: users
+    name: visits
+    type: integer
+    nullable: false
+    default: 0
+- add_index:
+    table: users
+    name: users_created
+    columns: [created_at]
+`)
+
+var portableComment = []byte(`
+- comment:
+    table: users
+    column: email
+    comment: Email address
+`)
+
+func TestRenderPortable(t *testing.T) {
+	stmts, err := statements(renderPortable(slices.Concat(portableUsers, portableComment), "postgres"))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`CREATE TYPE "users_status" AS ENUM ('active', 'disabled')`,
+		"CREATE TABLE \"users\" (\n\t\"id\" BIGINT NOT NULL,\n\t\"email\" VARCHAR(255) NOT NULL,\n\t\"status\" \"users_status\" DEFAULT 'active',\n\tPRIMARY KEY (\"id\")\n)",
+		`CREATE UNIQUE INDEX "idx_users_email" ON "users" ("email")`,
+		`COMMENT ON TABLE "users" IS 'Registered users'`,
+		`COMMENT ON COLUMN "users"."email" IS 'Login email'`,
+		`ALTER TABLE "users" ADD COLUMN "created_at" TIMESTAMP`,
+		`ALTER TABLE "users" ADD COLUMN "visits" BIGINT NOT NULL DEFAULT 0`,
+		`CREATE INDEX "users_created" ON "users" ("created_at")`,
+		`COMMENT ON COLUMN "users"."email" IS 'Email address'`,
+	}, stmts)
+
+	stmts, err = statements(renderPortable(portableUsers, "mysql"))
+	require.NoError(t, err)
+	require.Equal(t, "CREATE TABLE `users` (\n\t`id` BIGINT NOT NULL,\n\t`email` VARCHAR(255) NOT NULL COMMENT 'Login email',\n\t`status` ENUM('active', 'disabled') DEFAULT 'active',\n\tPRIMARY KEY (`id`)\n) COMMENT='Registered users'", stmts[0])
+
+	// Column comments would need MODIFY COLUMN with the full definition on mysql
+	_, err = renderPortable(portableComment, "mysql")
+	require.ErrorContains(t, err, "operation 1: comment on users.email isn't supported on mysql")
+
+	_, err = renderPortable([]byte("- add_column:\n    table: users\n    name: age\n    type: number\n"), "sqlite")
+	require.ErrorContains(t, err, `operation 1: column users.age has type "number"`)
+
+	_, err = renderPortable([]byte("- drop_table: users\n"), "sqlite")
+	require.ErrorContains(t, err, "unknown operation")
+}
+
+func TestRunPortable(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	options := &Options{
+		Project: "test",
+		Apply:   true,
+	}
+
+	fs := FS{
+		"1-users.up.yaml":  slices.Concat(portableUsers, portableComment),
+		"1-users.down.sql": []byte("DROP TABLE users;"),
+		"2-posts.up.sql":   []byte("CREATE TABLE posts (id integer);"),
+	}
+	require.Equal(t, []string{"1-users.up.yaml", "2-posts.up.sql"}, fs.Migrations())
+
+	down, ok := fs.Down("1-users.up.yaml")
+	require.True(t, ok)
+	require.Equal(t, "1-users.down.sql", down)
+
+	require.NoError(t, RunWithFS(ctx, db, fs, options))
+
+	tables, err := snapshot(ctx, db)
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+
+	users := tables[1]
+	require.Equal(t, "users", users.Name)
+	require.Len(t, users.Columns, 5)
+	require.Equal(t, []string{"active", "disabled"}, users.Columns[2].Values)
+	require.Len(t, users.Indexes, 3)
+
+	// Nullability and defaults are applied
+	_, err = db.ExecContext(ctx, "INSERT INTO users (id, email) VALUES (1, 'user@example.com')")
+	require.NoError(t, err)
+	var status string
+	var visits int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT status, visits FROM users").Scan(&status, &visits))
+	require.Equal(t, "active", status)
+	require.Equal(t, 0, visits)
+	_, err = db.ExecContext(ctx, "INSERT INTO users (id) VALUES (2)")
+	require.ErrorContains(t, err, "NOT NULL")
+
+	pending, err := Pending(ctx, db, fs, options)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
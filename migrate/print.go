@@ -9,9 +9,9 @@ import (
 
 // Print outputs database migrations for a project to log output.
 func Print(options *Options) error {
-	fs, ok := migrations[options.Project]
-	if !ok {
-		return errors.Errorf("Migrations for '%s' don't exist", options.Project)
+	fs, err := Loaded(options.Project)
+	if err != nil {
+		return err
 	}
 
 	printQuery := func(idx int, query string) error {
@@ -26,7 +26,7 @@ func Print(options *Options) error {
 
 	migrate := func(filename string) error {
 		log.Println("-- Migrations file:", filename)
-		stmts, err := statements(fs.ReadFile(filename))
+		stmts, err := statements(fs.readFor(filename, ""))
 		if err != nil {
 			return errors.Wrap(err, fmt.Sprintf("Error reading migration: %s", filename))
 		}
@@ -38,8 +38,24 @@ func Print(options *Options) error {
 		return nil
 	}
 
-	// print service migrations
-	for _, filename := range fs.Migrations() {
+	if len(options.InitSQL) > 0 {
+		log.Println("-- Init SQL")
+		for idx, stmt := range options.InitSQL {
+			if err := printQuery(idx, stmt); err != nil {
+				return err
+			}
+		}
+	}
+
+	// print hooks and service migrations
+	files := fs.Migrations()
+	if _, ok := fs[BeforeHook]; ok {
+		files = append([]string{BeforeHook}, files...)
+	}
+	if _, ok := fs[AfterHook]; ok {
+		files = append(files, AfterHook)
+	}
+	for _, filename := range files {
 		if err := migrate(filename); err != nil {
 			return err
 		}
@@ -0,0 +1,210 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report is a breakdown of the statement execution times of a
+// migration run, per file and per statement.
+type Report struct {
+	// Statements is the number of statements executed.
+	Statements int     `json:"statements"`
+	DurationMS float64 `json:"duration_ms"`
+
+	// ThresholdMS is the slow statement threshold, zero if disabled.
+	ThresholdMS float64 `json:"threshold_ms"`
+
+	// Slow holds the statements exceeding the threshold, slowest first.
+	Slow []*StatementReport `json:"slow"`
+
+	// Slowest holds the slowest statements.
+	Slowest []*StatementReport `json:"slowest"`
+
+	Files []*FileReport `json:"files"`
+}
+
+// FileReport holds the statements executed from a migration file on a database.
+type FileReport struct {
+	Database   string             `json:"database,omitempty"`
+	Project    string             `json:"project"`
+	Filename   string             `json:"filename"`
+	DurationMS float64            `json:"duration_ms"`
+	Statements []*StatementReport `json:"statements"`
+}
+
+// StatementReport holds the execution time of a statement.
+type StatementReport struct {
+	Database string `json:"database,omitempty"`
+	Project  string `json:"project"`
+	Filename string `json:"filename"`
+
+	// Index is the index of the statement in the file.
+	Index int    `json:"index"`
+	Query string `json:"query"`
+
+	DurationMS float64 `json:"duration_ms"`
+
+	// Slow is true if the statement exceeded the threshold.
+	Slow  bool   `json:"slow,omitempty"`
+	Error string `json:"error,omitempty"`
+
+	elapsed time.Duration
+}
+
+// NewReport returns the report for the statement timings of each database,
+// keyed by database name. Statements exceeding threshold are reported as
+// slow, and the slowest statements are listed, up to the count of slowest.
+func NewReport(timings map[string]*Timings, threshold time.Duration, slowest int) *Report {
+	result := &Report{
+		ThresholdMS: milliseconds(threshold),
+		Slow:        []*StatementReport{},
+		Slowest:     []*StatementReport{},
+		Files:       []*FileReport{},
+	}
+
+	databases := make([]string, 0, len(timings))
+	for database := range timings {
+		databases = append(databases, database)
+	}
+	sort.Strings(databases)
+
+	var total time.Duration
+	statements := []*StatementReport{}
+	for _, database := range databases {
+		var file *FileReport
+		for _, timing := range timings[database].Statements() {
+			if file == nil || file.Project != timing.Project || file.Filename != timing.Filename {
+				file = &FileReport{
+					Database:   database,
+					Project:    timing.Project,
+					Filename:   timing.Filename,
+					Statements: []*StatementReport{},
+				}
+				result.Files = append(result.Files, file)
+			}
+
+			statement := &StatementReport{
+				Database:   database,
+				Project:    timing.Project,
+				Filename:   timing.Filename,
+				Index:      timing.Index,
+				Query:      timing.Query,
+				DurationMS: milliseconds(timing.Duration),
+				Slow:       threshold > 0 && timing.Duration > threshold,
+				elapsed:    timing.Duration,
+			}
+			if timing.Err != nil {
+				statement.Error = timing.Err.Error()
+			}
+
+			file.Statements = append(file.Statements, statement)
+			file.DurationMS += statement.DurationMS
+			statements = append(statements, statement)
+			total += timing.Duration
+		}
+	}
+
+	sort.SliceStable(statements, func(i, j int) bool {
+		return statements[i].elapsed > statements[j].elapsed
+	})
+	for idx, statement := range statements {
+		if statement.Slow {
+			result.Slow = append(result.Slow, statement)
+		}
+		if idx < slowest {
+			result.Slowest = append(result.Slowest, statement)
+		}
+	}
+
+	result.Statements = len(statements)
+	result.DurationMS = milliseconds(total)
+	return result
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteMarkdown writes the report as markdown tables.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var sb strings.Builder
+
+	sb.WriteString("# Migration report\n\n")
+	fmt.Fprintf(&sb, "%d statements in %d files, %s total.", r.Statements, len(r.Files), formatMS(r.DurationMS))
+	if r.ThresholdMS > 0 {
+		fmt.Fprintf(&sb, " %d statements exceeded the %s threshold.", len(r.Slow), formatMS(r.ThresholdMS))
+	}
+	sb.WriteString("\n")
+
+	if len(r.Slowest) > 0 {
+		sb.WriteString("\n## Slowest statements\n\n")
+		sb.WriteString("| Duration | File | Statement | Query |\n")
+		sb.WriteString("|---:|---|---:|---|\n")
+		for _, statement := range r.Slowest {
+			fmt.Fprintf(&sb, "| %s | %s | %d | %s |\n", statement.duration(), statement.file(), statement.Index+1, markdownQuery(statement.Query))
+		}
+	}
+
+	for _, file := range r.Files {
+		fmt.Fprintf(&sb, "\n## %s\n\n", (&StatementReport{Database: file.Database, Project: file.Project, Filename: file.Filename}).file())
+		fmt.Fprintf(&sb, "%d statements, %s.\n\n", len(file.Statements), formatMS(file.DurationMS))
+		sb.WriteString("| Statement | Duration | Query |\n")
+		sb.WriteString("|---:|---:|---|\n")
+		for _, statement := range file.Statements {
+			fmt.Fprintf(&sb, "| %d | %s | %s |\n", statement.Index+1, statement.duration(), markdownQuery(statement.Query))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// duration returns the formatted duration, marked if slow or failed.
+func (s *StatementReport) duration() string {
+	result := formatMS(s.DurationMS)
+	if s.Slow {
+		result = "**" + result + "** (slow)"
+	}
+	if s.Error != "" {
+		result += " (failed)"
+	}
+	return result
+}
+
+// file returns the database, project and filename of the statement.
+func (s *StatementReport) file() string {
+	result := s.Project + "/" + s.Filename
+	if s.Database != "" {
+		result = s.Database + " " + result
+	}
+	return "`" + strings.ReplaceAll(result, "`", "'") + "`"
+}
+
+// markdownQuery returns the first line of a query for a table cell.
+func markdownQuery(query string) string {
+	line, _, more := strings.Cut(strings.TrimSpace(query), "\n")
+	line = strings.TrimSpace(line)
+	if len(line) > 80 {
+		line, more = line[:77], true
+	}
+	if more {
+		line += "..."
+	}
+	return "`" + strings.NewReplacer("`", "'", "|", "\\|").Replace(line) + "`"
+}
+
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1000
+}
+
+func formatMS(ms float64) string {
+	return time.Duration(ms * float64(time.Millisecond)).Round(10 * time.Microsecond).String()
+}
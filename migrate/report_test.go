@@ -0,0 +1,121 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	timings := &Timings{}
+	for _, timing := range []*StatementTiming{
+		{Project: "app", Filename: "1-users.up.sql", Index: 0, Query: "CREATE TABLE users (id integer)", Duration: 20 * time.Millisecond},
+		{Project: "app", Filename: "1-users.up.sql", Index: 1, Query: "CREATE INDEX users_id\n  ON users (id)", Duration: 1500 * time.Millisecond},
+		{Project: "app", Filename: "2-events.up.sql", Index: 0, Query: "INSERT INTO events VALUES ('a|b')", Duration: 5 * time.Millisecond, Err: errors.New("no such table: events")},
+	} {
+		timings.add(timing)
+	}
+
+	report := NewReport(map[string]*Timings{"primary": timings}, time.Second, 2)
+	require.Equal(t, 3, report.Statements)
+	require.Equal(t, 1525.0, report.DurationMS)
+	require.Len(t, report.Files, 2)
+	require.Equal(t, 1520.0, report.Files[0].DurationMS)
+	require.Len(t, report.Files[0].Statements, 2)
+	require.Len(t, report.Slow, 1)
+	require.Equal(t, 1, report.Slow[0].Index)
+	require.Len(t, report.Slowest, 2)
+	require.Equal(t, 20.0, report.Slowest[1].DurationMS)
+	require.Equal(t, "no such table: events", report.Files[1].Statements[0].Error)
+
+	var sb strings.Builder
+	require.NoError(t, report.WriteJSON(&sb))
+	decoded := &Report{}
+	require.NoError(t, json.Unmarshal([]byte(sb.String()), decoded))
+	require.Equal(t, report.Statements, decoded.Statements)
+	require.True(t, decoded.Slow[0].Slow)
+
+	sb.Reset()
+	require.NoError(t, report.WriteMarkdown(&sb))
+	markdown := sb.String()
+	require.Contains(t, markdown, "3 statements in 2 files, 1.525s total. 1 statements exceeded the 1s threshold.")
+	require.Contains(t, markdown, "| **1.5s** (slow) | `primary app/1-users.up.sql` | 2 | `CREATE INDEX users_id...` |")
+	require.Contains(t, markdown, "| 1 | 5ms (failed) | `INSERT INTO events VALUES ('a\\|b')` |")
+}
+
+func TestRunTimings(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	timings := &Timings{}
+	options := &Options{
+		Project: "test",
+		Apply:   true,
+		Timings: timings,
+	}
+
+	fs := FS{
+		"1-users.up.sql":  []byte("CREATE TABLE users (id integer);\nCREATE INDEX users_id ON users (id);"),
+		"2-events.up.sql": []byte("CREATE TABLE events (id integer);"),
+	}
+	require.NoError(t, RunWithFS(ctx, db, fs, options))
+
+	statements := timings.Statements()
+	require.Len(t, statements, 3)
+	require.Equal(t, "1-users.up.sql", statements[1].Filename)
+	require.Equal(t, 1, statements[1].Index)
+	require.NoError(t, statements[1].Err)
+
+	history, err := History(ctx, db, "test", 0)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	require.Equal(t, "2-events.up.sql", history[0].Filename)
+	require.Equal(t, "1-users.up.sql", history[2].Filename)
+	require.Equal(t, 0, history[2].StatementIndex)
+
+	history, err = History(ctx, db, "test", 1)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+
+	history, err = History(ctx, newTestDB(t), "test", 0)
+	require.NoError(t, err)
+	require.Empty(t, history)
+}
+
+func TestRunHistoryFailed(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	options := &Options{
+		Project: "test",
+		Apply:   true,
+	}
+
+	require.NoError(t, RunWithFS(ctx, db, FS{"1-users.up.sql": []byte("CREATE TABLE users (id integer);")}, options))
+
+	// The bookkeeping statements create the history table on every run,
+	// make the inserts fail instead.
+	_, err := db.ExecContext(ctx, "CREATE TRIGGER history_fail BEFORE INSERT ON "+HistoryTable+" BEGIN SELECT RAISE(ABORT, 'history is read-only'); END")
+	require.NoError(t, err)
+
+	// The statements are rolled back and the failure is recorded
+	fs := FS{"2-events.up.sql": []byte("CREATE TABLE events (id integer);")}
+	err = RunWithFS(ctx, db, fs, options)
+	require.ErrorIs(t, err, errHistory)
+
+	records, err := listMigrations(ctx, db, "test")
+	require.NoError(t, err)
+	record := records["2-events.up.sql"]
+	require.NotNil(t, record)
+	require.Equal(t, "2-events.up.sql", record.Filename)
+	require.Equal(t, -1, record.StatementIndex)
+	require.True(t, strings.HasPrefix(record.Status, "recording migration history failed: "), record.Status)
+	require.Contains(t, record.Status, "history is read-only")
+
+	exists, err := tableExists(ctx, db, "events")
+	require.NoError(t, err)
+	require.False(t, exists)
+}
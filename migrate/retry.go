@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// isTransient returns true if the error reported by the driver is
+// expected to go away when the statement or transaction is retried.
+func isTransient(driverName string, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch driverName {
+	case "mysql":
+		// 1213: deadlock found when trying to get lock
+		// 1205: lock wait timeout exceeded
+		var myErr *mysql.MySQLError
+		if errors.As(err, &myErr) {
+			return myErr.Number == 1213 || myErr.Number == 1205
+		}
+
+	case "postgres", "postgresql", "pgx":
+		// Both pgx and lib/pq errors expose the SQLSTATE code.
+		// 40001: serialization_failure
+		// 40P01: deadlock_detected
+		// 55P03: lock_not_available (lock_timeout)
+		var pgErr interface{ SQLState() string }
+		if errors.As(err, &pgErr) {
+			switch pgErr.SQLState() {
+			case "40001", "40P01", "55P03":
+				return true
+			}
+		}
+
+	case "sqlite":
+		// SQLITE_BUSY (5) and SQLITE_LOCKED (6), including extended codes.
+		var liteErr interface{ Code() int }
+		if errors.As(err, &liteErr) {
+			switch liteErr.Code() & 0xff {
+			case 5, 6:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retryDelay returns the backoff delay before the given retry attempt (1-based).
+func (options *Options) retryDelay(attempt int) time.Duration {
+	delay := options.RetryDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if options.RetryMaxDelay > 0 && delay >= options.RetryMaxDelay {
+			return options.RetryMaxDelay
+		}
+	}
+	return delay
+}
+
+// retry invokes fn and retries it with backoff while it fails with a transient error.
+// Each retry is reported to the log output with the name of the retried unit,
+// and recorded as an event on the current span.
+func retry(ctx context.Context, options *Options, driverName string, name string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt > options.Retries || !isTransient(driverName, err) {
+			return err
+		}
+
+		delay := options.retryDelay(attempt)
+		options.logger().Printf("%s RETRY %d/%d in %s: %s", name, attempt, options.Retries, delay, err)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.String("mig.retry.name", name),
+			attribute.Int("mig.retry.attempt", attempt),
+			attribute.String("mig.retry.delay", delay.String()),
+			attribute.String("error", err.Error()),
+		))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+type codeError int
+
+func (e codeError) Error() string { return fmt.Sprintf("code %d", int(e)) }
+func (e codeError) Code() int     { return int(e) }
+
+func TestIsTransient(t *testing.T) {
+	testcases := []struct {
+		driver string
+		err    error
+		want   bool
+	}{
+		{"mysql", &mysql.MySQLError{Number: 1213}, true},
+		{"mysql", &mysql.MySQLError{Number: 1205}, true},
+		{"mysql", fmt.Errorf("wrapped: %w", &mysql.MySQLError{Number: 1213}), true},
+		{"mysql", &mysql.MySQLError{Number: 1050}, false},
+		{"pgx", sqlStateError("40001"), true},
+		{"postgres", sqlStateError("40P01"), true},
+		{"pgx", sqlStateError("55P03"), true},
+		{"pgx", sqlStateError("42P07"), false},
+		{"sqlite", codeError(5), true},
+		{"sqlite", codeError(517), true},
+		{"sqlite", codeError(1), false},
+		{"sqlite", errors.New("database is locked"), false},
+		{"mysql", nil, false},
+	}
+
+	for _, tc := range testcases {
+		require.Equal(t, tc.want, isTransient(tc.driver, tc.err), "%s: %v", tc.driver, tc.err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	options := &Options{
+		Retries: 2,
+	}
+
+	calls := 0
+	err := retry(ctx, options, "sqlite", "test", func() error {
+		calls++
+		if calls < 3 {
+			return codeError(5)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	calls = 0
+	err = retry(ctx, options, "sqlite", "test", func() error {
+		calls++
+		return codeError(5)
+	})
+	require.Error(t, err)
+	require.Equal(t, 3, calls)
+
+	calls = 0
+	err = retry(ctx, options, "sqlite", "test", func() error {
+		calls++
+		return codeError(1)
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestRetryDelay(t *testing.T) {
+	options := NewOptions()
+
+	require.Equal(t, options.RetryDelay, options.retryDelay(1))
+	require.Equal(t, 2*options.RetryDelay, options.retryDelay(2))
+	require.Equal(t, options.RetryMaxDelay, options.retryDelay(10))
+}
+
+func TestRunRetriesExhausted(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	// Another connection holds the write lock of an attached
+	// database, so writing to it fails with SQLITE_BUSY.
+	filename := filepath.Join(t.TempDir(), "locked.db")
+	holder, err := sql.Open("sqlite", filename)
+	require.NoError(t, err)
+	t.Cleanup(func() { holder.Close() })
+
+	conn, err := holder.Conn(ctx)
+	require.NoError(t, err)
+	_, err = conn.ExecContext(ctx, "CREATE TABLE held (id integer)")
+	require.NoError(t, err)
+	_, err = conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.ExecContext(ctx, "ROLLBACK")
+		conn.Close()
+	})
+
+	fs := FS{
+		"1-users.up.sql":  []byte("CREATE TABLE users (id integer);"),
+		"2-locked.up.sql": []byte("CREATE TABLE locked.events (id integer);"),
+	}
+	options := &Options{
+		Project:    "test",
+		Apply:      true,
+		InitSQL:    []string{"ATTACH DATABASE '" + filename + "' AS locked"},
+		Retries:    1,
+		RetryDelay: time.Millisecond,
+	}
+
+	runErr := RunWithFS(ctx, db, fs, options)
+	require.Error(t, runErr)
+	require.True(t, isTransient("sqlite", runErr), runErr.Error())
+
+	// The file is recorded as failed after the last retry
+	files, err := Status(ctx, db, fs, options)
+	require.NoError(t, err)
+	require.Equal(t, StateApplied, files[0].State)
+	require.Equal(t, StateFailed, files[1].State)
+	require.Equal(t, 0, files[1].Applied)
+	require.Equal(t, runErr.Error(), files[1].Error)
+}
@@ -2,11 +2,13 @@ package migrate
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"strings"
+	"time"
 
 	"database/sql"
+	"database/sql/driver"
 
 	"github.com/jmoiron/sqlx"
 
@@ -24,166 +26,579 @@ func Run(ctx context.Context, dbOptions *db.Options, options *Options) error {
 }
 
 // RunWithDB runs the registered migrations from options against a *sqlx.DB with context.
+// The migrations don't run if the requirements from the project manifest aren't met.
 func RunWithDB(ctx context.Context, sqldb *sqlx.DB, options *Options) error {
-	fs, ok := migrations[options.Project]
-	if !ok {
-		return fmt.Errorf("Migrations for '%s' don't exist", options.Project)
+	fs, err := Loaded(options.Project)
+	if err != nil {
+		return err
+	}
+
+	if err := CheckRequirements(ctx, sqldb, options); err != nil {
+		return err
 	}
 
 	return RunWithFS(ctx, sqldb, fs, options)
 }
 
 // RunWithFS runs the passed migrations against a *sqlx.DB with context.
-func RunWithFS(ctx context.Context, sqldb *sqlx.DB, fs FS, options *Options) error {
-	// Normalize driver name for migration file lookup
-	driverName := sqldb.DriverName()
-	if driverName == "pgx" {
-		driverName = "postgres"
-	}
-	migrationFile := fmt.Sprintf("migrations-%s.sql", driverName)
+func RunWithFS(ctx context.Context, sqldb *sqlx.DB, fs FS, options *Options) (err error) {
+	r := newRunner(sqldb, fs, options)
+
+	ctx, end := r.startSpan(ctx, "mig.run", attrProject.String(options.Project), attrDBSystem.String(r.driver))
+	defer func() {
+		end(err)
+	}()
+
+	migrationFile := r.migrationsFile()
 	migrationTable, err := statements(migrationsFS.ReadFile(migrationFile))
 	if err != nil {
 		return fmt.Errorf("error reading %s: %w", migrationFile, err)
 	}
 
-	printQuery := func(idx int, query string) {
-		if options.Verbose {
-			fmt.Println()
-			fmt.Println("-- Statement index:", idx)
-			fmt.Println(query)
-			fmt.Println()
-		}
+	// All statements run on a single connection, so session
+	// settings apply to every statement and transaction.
+	release, err := r.open(ctx, sqldb)
+	if err != nil {
+		return err
 	}
+	defer release()
 
-	execQuery := func(idx int, query string) error {
-		printQuery(idx, query)
-		if _, err := sqldb.ExecContext(ctx, query); err != nil && err != sql.ErrNoRows {
+	if err := r.session(ctx); err != nil {
+		return err
+	}
+
+	// Run main migration (schema creation for migrations table itself)
+	if err := r.execAll(ctx, migrationFile, migrationTable); err != nil {
+		return err
+	}
+
+	// Run service migrations, retrying the whole file on transient errors
+	for _, filename := range fs.Migrations() {
+		if r.stopped() {
+			options.logger().Println(filename, "NOT STARTED (stopped)")
+			return ErrStopped
+		}
+		if err := retry(ctx, options, r.driver, filename, func() error {
+			return r.migrate(ctx, filename)
+		}); err != nil {
+			// The transaction of a transient error is rolled back
+			// without saving the status, record it after the last retry.
+			if isTransient(r.driver, err) {
+				return r.fail(ctx, filename, err)
+			}
 			return err
 		}
-		return nil
 	}
 
-	// execQueryInTransaction executes a query within the context of a transaction
-	execQueryInTransaction := func(tx *sqlx.Tx, idx int, query string) error {
-		printQuery(idx, query)
-		if _, err := tx.ExecContext(ctx, query); err != nil && err != sql.ErrNoRows {
-			return err
+	if r.stopped() {
+		return ErrStopped
+	}
+	return r.hook(ctx, AfterHook)
+}
+
+// stopped returns true if a graceful stop has been requested.
+func (r *runner) stopped() bool {
+	select {
+	case <-r.options.Stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// driverName returns the driver name used for migration file lookup.
+func driverName(sqldb *sqlx.DB) string {
+	driverName := sqldb.DriverName()
+	if driverName == "pgx" {
+		return "postgres"
+	}
+	return driverName
+}
+
+// runner applies migrations from a FS over a single database connection.
+type runner struct {
+	fs      FS
+	options *Options
+
+	// driver is the normalized driver name (pgx is postgres).
+	driver string
+	conn   *sqlx.Conn
+
+	telemetry *telemetry
+}
+
+// newRunner returns a runner for the migrations in fs. The connection
+// is taken from sqldb with open.
+func newRunner(sqldb *sqlx.DB, fs FS, options *Options) *runner {
+	return &runner{
+		fs:        fs,
+		options:   options,
+		driver:    driverName(sqldb),
+		telemetry: newTelemetry(options),
+	}
+}
+
+// migrationsFile returns the name of the file creating the bookkeeping tables.
+func (r *runner) migrationsFile() string {
+	return fmt.Sprintf("migrations-%s.sql", r.driver)
+}
+
+// open takes the connection for the runner from the pool, and returns a
+// function releasing it. Migration files, --init-sql and hook statements
+// may change session settings, e.g. `SET ROLE migrator`, which would
+// apply to other queries using the pool. On mysql and postgres, the
+// connection is closed instead of being returned to the pool. On sqlite,
+// where closing the connection drops an in-memory database, the
+// connection settings are restored.
+func (r *runner) open(ctx context.Context, sqldb *sqlx.DB) (func(), error) {
+	conn, err := sqldb.Connx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection: %w", err)
+	}
+	r.conn = conn
+
+	if r.driver != "sqlite" {
+		return func() {
+			discard(conn)
+			conn.Close()
+		}, nil
+	}
+
+	restore, err := db.SaveSQLiteSession(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return func() {
+		// The run context may be cancelled on a forced stop.
+		if err := restore(context.WithoutCancel(ctx)); err != nil {
+			r.options.logger().Println("closing the migration connection:", err)
+			discard(conn)
 		}
+		conn.Close()
+	}, nil
+}
+
+// discard closes a connection instead of returning it to the pool.
+func discard(conn *sqlx.Conn) {
+	conn.Raw(func(any) error {
+		return driver.ErrBadConn
+	})
+}
+
+// session applies the session settings to the connection, and runs
+// the init statements and the before hook.
+func (r *runner) session(ctx context.Context) error {
+	if r.driver == "mysql" && r.options.StatementTimeout > 0 {
+		r.options.logger().Println("warning: the statement timeout only limits SELECT statements on mysql, DDL and DML statements run without a timeout")
+	}
+	if err := db.SetSessionTimeouts(ctx, r.conn, r.driver, r.options.StatementTimeout, r.options.LockTimeout); err != nil {
+		return err
+	}
+	if err := r.execAll(ctx, "--init-sql", r.options.InitSQL); err != nil {
+		return err
+	}
+	return r.hook(ctx, BeforeHook)
+}
+
+// hook executes the statements of a hook file, if it exists.
+func (r *runner) hook(ctx context.Context, filename string) error {
+	if _, ok := r.fs[filename]; !ok {
 		return nil
 	}
 
-	migrate := func(filename string) error {
-		status := Migration{
-			Project:        options.Project,
-			Filename:       filename,
-			StatementIndex: -1,
-		}
+	stmts, err := statements(r.fs.readFor(filename, r.driver))
+	if err != nil {
+		return fmt.Errorf("Error reading %s: %w", filename, err)
+	}
+	if err := r.execAll(ctx, filename, stmts); err != nil {
+		return err
+	}
 
-		// Use a transaction with advisory lock to handle concurrent migrations safely
-		tx, err := sqldb.BeginTxx(ctx, nil)
-		if err != nil {
-			return fmt.Errorf("failed to begin transaction: %w", err)
+	r.options.logger().Println(filename, "OK")
+	return nil
+}
+
+// execAll executes statements on the connection, outside of a transaction.
+// Each statement is retried on transient errors.
+func (r *runner) execAll(ctx context.Context, name string, stmts []string) error {
+	for idx, stmt := range stmts {
+		if err := retry(ctx, r.options, r.driver, fmt.Sprintf("%s statement %d", name, idx), func() error {
+			return r.exec(ctx, r.conn, name, idx, stmt)
+		}); err != nil {
+			return fmt.Errorf("%s statement %d failed: %w", name, idx, err)
 		}
-		defer tx.Rollback()
+	}
+	return nil
+}
+
+// lock acquires the migration lock for a file within the transaction.
+func (r *runner) lock(ctx context.Context, tx *sqlx.Tx, filename string) error {
+	start := time.Now()
+	if err := db.AcquireLock(ctx, tx, r.driver, r.lockKey(filename)); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	r.recordLockWait(ctx, time.Since(start))
+	return nil
+}
+
+// lockKey returns the lock key for a migration file. The schema of a
+// schema target is included, so migrations for tenants in different
+// schemas don't block each other.
+func (r *runner) lockKey(filename string) string {
+	if r.options.Schema != "" {
+		return fmt.Sprintf("%s:%s:%s", r.options.Schema, r.options.Project, filename)
+	}
+	return fmt.Sprintf("%s:%s", r.options.Project, filename)
+}
+
+func (r *runner) printQuery(idx int, query string) {
+	if r.options.Verbose {
+		fmt.Println()
+		fmt.Println("-- Statement index:", idx)
+		fmt.Println(query)
+		fmt.Println()
+	}
+}
+
+// exec executes a query from a file on the connection or within the context of a transaction.
+func (r *runner) exec(ctx context.Context, execer sqlx.ExecerContext, filename string, idx int, query string) (err error) {
+	r.printQuery(idx, query)
+
+	ctx, end := r.startSpan(ctx, "mig.statement", r.statementAttributes(filename, idx, query)...)
+	start := time.Now()
+	defer func() {
+		r.recordStatement(ctx, filename, time.Since(start), err)
+		end(err)
+	}()
+
+	// SQLite has no statement timeout setting, bound statements with a deadline instead.
+	if r.driver == "sqlite" && r.options.StatementTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.options.StatementTimeout)
+		defer cancel()
+	}
+
+	if _, err := execer.ExecContext(ctx, query); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+// saveStatus inserts or updates the migration record within the transaction.
+func (r *runner) saveStatus(ctx context.Context, tx *sqlx.Tx, status *Migration, exists bool) error {
+	query := "INSERT INTO migrations (project, filename, statement_index, status) VALUES (:project, :filename, :statement_index, :status)"
+	if exists {
+		query = "UPDATE migrations SET statement_index=:statement_index, status=:status WHERE project=:project AND filename=:filename"
+	}
+	if _, err := tx.NamedExecContext(ctx, query, status); err != nil {
+		return fmt.Errorf("updating migration state failed: %w", err)
+	}
+	return nil
+}
+
+// records returns the recorded migrations for the project within the transaction.
+func (r *runner) records(ctx context.Context, tx *sqlx.Tx) (map[string]*Migration, error) {
+	rows := []*Migration{}
+	query := tx.Rebind("select * from migrations where project=?")
+	if err := tx.SelectContext(ctx, &rows, query, r.options.Project); err != nil {
+		return nil, fmt.Errorf("error listing migrations: %w", err)
+	}
+	result := map[string]*Migration{}
+	for _, row := range rows {
+		result[row.Filename] = row
+	}
+	return result, nil
+}
 
-		// Acquire lock to prevent concurrent migrations from interfering
-		lockKey := fmt.Sprintf("%s:%s", status.Project, status.Filename)
-		if err := db.AcquireLock(ctx, tx, sqldb.DriverName(), lockKey); err != nil {
-			return fmt.Errorf("failed to acquire migration lock: %w", err)
+// migrate applies the statements of a migration file that haven't been applied yet.
+func (r *runner) migrate(ctx context.Context, filename string) (err error) {
+	ctx, end := r.startSpan(ctx, "mig.file", attrProject.String(r.options.Project), attrFile.String(filename))
+	defer func() {
+		end(err)
+	}()
+
+	status := &Migration{
+		Project:        r.options.Project,
+		Filename:       filename,
+		StatementIndex: -1,
+	}
+
+	contents, err := r.fs.readFor(filename, r.driver)
+	if err != nil {
+		return fmt.Errorf("Error reading %s: %w", filename, err)
+	}
+
+	script := parse(contents)
+	stmts := script.statements
+	checks, err := assertions(script.directives)
+	if err != nil {
+		return fmt.Errorf("Error reading %s: %w", filename, err)
+	}
+
+	// Use a transaction with advisory lock to handle concurrent migrations safely
+	tx, err := r.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Acquire lock to prevent concurrent migrations from interfering
+	if err := r.lock(ctx, tx, status.Filename); err != nil {
+		return err
+	}
+
+	// Re-check if migration record exists under lock
+	query := tx.Rebind("select * from migrations where project=? and filename=?")
+	exists := true
+	if err := tx.GetContext(ctx, status, query, status.Project, status.Filename); err != nil {
+		if err == sql.ErrNoRows {
+			exists = false
+		} else {
+			return err
 		}
+	}
 
-		// Re-check if migration record exists under lock
-		query := sqldb.Rebind("select * from migrations where project=? and filename=?")
-		exists := true
-		if err := tx.GetContext(ctx, &status, query, status.Project, status.Filename); err != nil {
-			if err == sql.ErrNoRows {
-				exists = false
-			} else {
+	// A baseline isn't applied if the files it replaces have been
+	// applied, it's recorded as applied instead.
+	if !exists {
+		if replaced := replacedFiles(script.directives); len(replaced) > 0 {
+			records, err := r.records(ctx, tx)
+			if err != nil {
 				return err
 			}
-		}
-
-		// If migration already exists and is marked ok, check if new
-		// statements were appended since the last run.
-		if exists && status.Status == "ok" {
-			stmts, err := statements(fs.ReadFile(filename))
+			applied, err := replacedApplied(filename, replaced, records)
 			if err != nil {
-				return fmt.Errorf("Error reading %s: %w", filename, err)
+				return err
 			}
-			if len(stmts) <= status.StatementIndex+1 {
+			if applied {
+				status.StatementIndex = len(stmts) - 1
+				status.Status = StatusOK
+				if err := r.saveStatus(ctx, tx, status, exists); err != nil {
+					return err
+				}
 				if err := tx.Commit(); err != nil {
 					return fmt.Errorf("failed to commit transaction: %w", err)
 				}
-				log.Println(filename, "SKIPPED (already applied)")
+				r.options.logger().Println(filename, "SKIPPED (replaced migrations already applied)")
 				return nil
 			}
 		}
+	}
 
-		up := func() error {
-			stmts, err := statements(fs.ReadFile(filename))
+	// If migration already exists and is marked ok, check if new
+	// statements were appended since the last run.
+	if exists && status.Status == StatusOK && len(stmts) <= status.StatementIndex+1 {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		r.options.logger().Println(filename, "SKIPPED (already applied)")
+		return nil
+	}
+
+	// Keep the state from before the file is applied, to record
+	// failed assertions after the transaction is rolled back.
+	initial := *status
+
+	// Without transactional DDL, every statement commits implicitly. Record
+	// progress after each statement, so an interrupted run leaves a record
+	// of the statements that have already been applied.
+	transactional := db.TransactionalDDL(r.driver)
+
+	up := func() error {
+		var isApplied bool
+		for idx, stmt := range stmts {
+			isApplied = idx <= status.StatementIndex
+			if r.options.Verbose {
+				fmt.Printf("-- statement %d/%d is applied? %t\n", idx, status.StatementIndex, isApplied)
+			}
+			// skip stmt if it has already been applied
+			if isApplied {
+				r.printQuery(idx, stmt.query)
+				continue
+			}
+
+			if r.stopped() {
+				return ErrStopped
+			}
+
+			status.StatementIndex = idx
+			start := time.Now()
+			err := r.exec(ctx, tx, filename, idx, stmt.query)
+			r.options.Timings.add(&StatementTiming{
+				Project:  r.options.Project,
+				Filename: filename,
+				Index:    idx,
+				Query:    stmt.query,
+				Duration: time.Since(start),
+				Err:      err,
+			})
 			if err != nil {
-				return fmt.Errorf("Error reading %s: %w", filename, err)
+				status.StatementIndex--
+				status.Status = err.Error()
+				return err
+			}
+			if err := r.recordHistory(ctx, tx, filename, idx, time.Since(start)); err != nil {
+				status.Status = err.Error()
+				return err
 			}
 
-			var isApplied bool
-			for idx, stmt := range stmts {
-				isApplied = idx <= status.StatementIndex
-				if options.Verbose {
-					fmt.Printf("-- statement %d/%d is applied? %t\n", idx, status.StatementIndex, isApplied)
-				}
-				// skip stmt if it has already been applied
-				if !isApplied {
-					status.StatementIndex = idx
-					if err := execQueryInTransaction(tx, idx, stmt); err != nil {
-						status.StatementIndex--
-						status.Status = err.Error()
-						return err
-					}
-				} else {
-					printQuery(idx, stmt)
+			if !transactional {
+				status.Status = StatusRunning
+				if err := r.saveStatus(ctx, tx, status, exists); err != nil {
+					return err
 				}
+				exists = true
 			}
-			status.Status = "ok"
-			return nil
 		}
+		status.Status = StatusOK
+		return nil
+	}
 
-		err = up()
+	err = up()
 
-		// Save migration status to database within transaction
-		if exists {
-			// UPDATE existing record
-			updateQuery := "UPDATE migrations SET statement_index=:statement_index, status=:status WHERE project=:project AND filename=:filename"
-			if _, err := tx.NamedExecContext(ctx, updateQuery, status); err != nil {
-				return fmt.Errorf("updating migration state failed: %w", err)
-			}
-		} else {
-			// INSERT new record
-			insertQuery := "INSERT INTO migrations (project, filename, statement_index, status) VALUES (:project, :filename, :statement_index, :status)"
-			if _, err := tx.NamedExecContext(ctx, insertQuery, status); err != nil {
-				return fmt.Errorf("updating migration state failed: %w", err)
+	// Persist the statements applied before a graceful stop, so the
+	// next run resumes with the next statement.
+	if errors.Is(err, ErrStopped) {
+		return r.stop(ctx, tx, &initial, status, exists, len(stmts))
+	}
+
+	// Transient errors leave the transaction unusable (postgres aborts it,
+	// mysql rolls it back on deadlock). Roll back without saving the status,
+	// so the file can be retried from the last recorded state. When the
+	// retries are exhausted, the failure is recorded by fail.
+	if isTransient(r.driver, err) {
+		return err
+	}
+
+	// A failed history insert aborts the transaction on postgres. With
+	// transactional DDL, the statements are rolled back and the failure is
+	// recorded against the previous state, like a failed assertion.
+	if errors.Is(err, errHistory) && transactional {
+		initial.Status = status.Status
+		return r.rollback(ctx, tx, &initial, exists, err)
+	}
+
+	// Check the schema matches the assertions in the file. With transactional
+	// DDL, the statements are rolled back and the failure is recorded against
+	// the previous state, so the file is applied again on the next run.
+	if err == nil {
+		if err = checkAssertions(ctx, tx, checks); err != nil {
+			status.Status = err.Error()
+			if transactional {
+				initial.Status = status.Status
+				return r.rollback(ctx, tx, &initial, exists, err)
 			}
 		}
+	}
 
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("failed to commit transaction: %w", err)
-		}
+	// Save migration status to database within transaction
+	if err := r.saveStatus(ctx, tx, status, exists); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.options.logger().Println(filename, strings.ToUpper(status.Status))
+	return err
+}
+
+// stop records the progress of a stopped migration file and commits
+// the transaction. If no statements were applied, it's rolled back.
+// It returns ErrStopped.
+func (r *runner) stop(ctx context.Context, tx *sqlx.Tx, initial, status *Migration, exists bool, total int) error {
+	if status.StatementIndex == initial.StatementIndex {
+		r.options.logger().Println(status.Filename, "NOT STARTED (stopped)")
+		return ErrStopped
+	}
+
+	status.Status = StatusRunning
+	if err := r.saveStatus(ctx, tx, status, exists); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.options.logger().Printf("%s STOPPED (statements 1-%d of %d applied)", status.Filename, status.StatementIndex+1, total)
+	return ErrStopped
+}
+
+// rollback rolls back the transaction and records the migration
+// status in a new transaction. It returns the passed error.
+func (r *runner) rollback(ctx context.Context, tx *sqlx.Tx, status *Migration, exists bool, cause error) error {
+	if err := tx.Rollback(); err != nil {
+		return fmt.Errorf("failed to roll back transaction: %w", err)
+	}
 
-		log.Println(filename, strings.ToUpper(status.Status))
+	tx, err := r.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.lock(ctx, tx, status.Filename); err != nil {
 		return err
 	}
 
-	// Run main migration (schema creation for migrations table itself)
-	for idx, stmt := range migrationTable {
-		if err := execQuery(idx, stmt); err != nil {
+	if err := r.saveStatus(ctx, tx, status, exists); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.options.logger().Println(status.Filename, "ROLLED BACK:", strings.ToUpper(status.Status))
+	return cause
+}
+
+// fail records a migration file as failed in a new transaction, after
+// a transient error rolled back the transaction applying it. The
+// statements recorded as applied are kept. It returns the passed error.
+func (r *runner) fail(ctx context.Context, filename string, cause error) error {
+	tx, err := r.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w (recording the failure: failed to begin transaction: %v)", cause, err)
+	}
+	defer tx.Rollback()
+
+	save := func() error {
+		if err := r.lock(ctx, tx, filename); err != nil {
 			return err
 		}
-	}
 
-	// Run service migrations
-	for _, filename := range fs.Migrations() {
-		if err := migrate(filename); err != nil {
+		status := &Migration{
+			Project:        r.options.Project,
+			Filename:       filename,
+			StatementIndex: -1,
+		}
+		query := tx.Rebind("select * from migrations where project=? and filename=?")
+		exists := true
+		if err := tx.GetContext(ctx, status, query, status.Project, status.Filename); err != nil {
+			if err != sql.ErrNoRows {
+				return err
+			}
+			exists = false
+		}
+
+		status.Status = cause.Error()
+		if err := r.saveStatus(ctx, tx, status, exists); err != nil {
 			return err
 		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
 	}
-	return nil
+
+	if err := save(); err != nil {
+		return fmt.Errorf("%w (recording the failure: %v)", cause, err)
+	}
+
+	r.options.logger().Println(filename, "FAILED (retries exhausted):", cause)
+	return cause
 }
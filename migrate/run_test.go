@@ -0,0 +1,209 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"modernc.org/sqlite"
+)
+
+func newTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	handle, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { handle.Close() })
+
+	// keep a single connection, each :memory: connection is a new database
+	handle.SetMaxOpenConns(1)
+
+	return sqlx.NewDb(handle, "sqlite")
+}
+
+func TestRunSessionTimeouts(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	contents, err := testdataFS.ReadFile("testdata/pulse.up.sql")
+	require.NoError(t, err)
+
+	_, err = db.ExecContext(ctx, "PRAGMA busy_timeout = 250")
+	require.NoError(t, err)
+
+	fs := FS{
+		"pulse.up.sql": contents,
+		AfterHook:      []byte("CREATE TABLE session_timeouts AS SELECT * FROM pragma_busy_timeout;"),
+	}
+	err = RunWithFS(ctx, db, fs, &Options{
+		Project:          "test",
+		Apply:            true,
+		LockTimeout:      1500 * time.Millisecond,
+		StatementTimeout: time.Second,
+	})
+	require.NoError(t, err)
+
+	// The timeout applies to the migrations
+	var busyTimeout int
+	require.NoError(t, db.GetContext(ctx, &busyTimeout, "SELECT * FROM session_timeouts"))
+	require.Equal(t, 1500, busyTimeout)
+
+	// and it's restored before the connection is returned to the pool
+	require.NoError(t, db.GetContext(ctx, &busyTimeout, "PRAGMA busy_timeout"))
+	require.Equal(t, 250, busyTimeout)
+}
+
+func TestRunHooks(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	// Temporary tables are only visible on the connection that
+	// created them, so the hooks must share the runner connection.
+	fs := FS{
+		BeforeHook:       []byte("INSERT INTO session_log VALUES ('before');"),
+		"1-users.up.sql": []byte("CREATE TABLE users (id integer);\nINSERT INTO session_log VALUES ('users');"),
+		AfterHook:        []byte("CREATE TABLE hooks AS SELECT name FROM session_log;"),
+	}
+
+	err := RunWithFS(ctx, db, fs, &Options{
+		Project: "test",
+		Apply:   true,
+		InitSQL: []string{"CREATE TEMP TABLE session_log (name text)"},
+	})
+	require.NoError(t, err)
+
+	var names []string
+	require.NoError(t, db.SelectContext(ctx, &names, "SELECT name FROM hooks"))
+	require.Equal(t, []string{"before", "users"}, names)
+
+	// Hook files are not recorded as migrations
+	files, err := Status(ctx, db, fs, &Options{Project: "test"})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
+func TestRunSessionReset(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	attached := filepath.Join(t.TempDir(), "attached.db")
+	fs := FS{
+		BeforeHook:       []byte("PRAGMA recursive_triggers = ON;"),
+		"1-users.up.sql": []byte("CREATE TABLE users (id integer);"),
+	}
+	err := RunWithFS(ctx, db, fs, &Options{
+		Project: "test",
+		Apply:   true,
+		InitSQL: []string{
+			"PRAGMA foreign_keys = ON",
+			"ATTACH DATABASE '" + attached + "' AS attached",
+		},
+	})
+	require.NoError(t, err)
+
+	// The pooled connection is back to its original settings
+	var foreignKeys, recursiveTriggers int
+	require.NoError(t, db.GetContext(ctx, &foreignKeys, "PRAGMA foreign_keys"))
+	require.NoError(t, db.GetContext(ctx, &recursiveTriggers, "PRAGMA recursive_triggers"))
+	require.Equal(t, 0, foreignKeys)
+	require.Equal(t, 0, recursiveTriggers)
+
+	var databases []string
+	require.NoError(t, db.SelectContext(ctx, &databases, "SELECT name FROM pragma_database_list"))
+	require.Equal(t, []string{"main"}, databases)
+}
+
+func TestDiscard(t *testing.T) {
+	ctx := context.Background()
+
+	handle, err := sqlx.Open("sqlite", filepath.Join(t.TempDir(), "discard.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { handle.Close() })
+
+	conn, err := handle.Connx(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, handle.Stats().OpenConnections)
+
+	// A discarded connection isn't returned to the pool
+	discard(conn)
+	conn.Close()
+	require.Equal(t, 0, handle.Stats().OpenConnections)
+}
+
+var (
+	// testStop is called by the mig_test_stop() SQL function.
+	testStop     func()
+	testStopOnce sync.Once
+)
+
+// registerTestStop sets the function called by mig_test_stop() for the test.
+// SQL functions are registered process-wide, so it's registered only once.
+func registerTestStop(t *testing.T, fn func()) {
+	t.Helper()
+
+	var err error
+	testStopOnce.Do(func() {
+		err = sqlite.RegisterScalarFunction("mig_test_stop", 0, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+			if testStop != nil {
+				testStop()
+			}
+			return nil, nil
+		})
+	})
+	require.NoError(t, err)
+
+	testStop = fn
+	t.Cleanup(func() { testStop = nil })
+}
+
+func TestRunStop(t *testing.T) {
+	ctx := context.Background()
+
+	// The second statement requests a graceful stop while it's executed.
+	stop := make(chan struct{})
+	registerTestStop(t, func() { close(stop) })
+
+	db := newTestDB(t)
+	fs := FS{
+		"1-users.up.sql":  []byte("CREATE TABLE users (id integer);\nSELECT mig_test_stop();\nCREATE TABLE groups (id integer);"),
+		"2-events.up.sql": []byte("CREATE TABLE events (id integer);"),
+	}
+	options := &Options{
+		Project: "test",
+		Apply:   true,
+		Stop:    stop,
+	}
+
+	err := RunWithFS(ctx, db, fs, options)
+	require.ErrorIs(t, err, ErrStopped)
+
+	files, err := Status(ctx, db, fs, options)
+	require.NoError(t, err)
+	require.Equal(t, StatePartial, files[0].State)
+	require.Equal(t, 2, files[0].Applied)
+	require.Equal(t, StatePending, files[1].State)
+
+	// The next run resumes with the third statement
+	options.Stop = nil
+	require.NoError(t, RunWithFS(ctx, db, fs, options))
+
+	files, err = Status(ctx, db, fs, options)
+	require.NoError(t, err)
+	require.Equal(t, StateApplied, files[0].State)
+	require.Equal(t, StateApplied, files[1].State)
+}
+
+func TestLockKey(t *testing.T) {
+	r := &runner{options: &Options{Project: "app"}}
+	require.Equal(t, "app:1-users.up.sql", r.lockKey("1-users.up.sql"))
+
+	// Schema targets lock the files within the schema
+	r.options.Schema = "tenant_a"
+	require.Equal(t, "tenant_a:app:1-users.up.sql", r.lockKey("1-users.up.sql"))
+}
@@ -0,0 +1,352 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Safety issue severities.
+const (
+	// SeverityError marks statements that lose data or break running applications.
+	SeverityError = "error"
+
+	// SeverityWarning marks statements that may lock tables or need review.
+	SeverityWarning = "warning"
+)
+
+// SafetyIssue is a risky statement found in a pending migration file.
+type SafetyIssue struct {
+	Filename string
+	Line     int
+
+	// Index is the statement index within the migration file.
+	Index int
+
+	Rule     string
+	Severity string
+	Message  string
+	Query    string
+}
+
+// String returns a one line description of the issue.
+func (i *SafetyIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s [%s] %s", i.Filename, i.Line, i.Severity, i.Rule, i.Message)
+}
+
+// safetyRule checks a normalized (uppercase, single spaced) statement.
+type safetyRule struct {
+	id       string
+	severity string
+	message  string
+
+	// drivers limits the rule to the listed drivers, empty means all drivers.
+	drivers []string
+
+	// match returns true if the statement is risky.
+	match func(query string, file *safetyContext) bool
+}
+
+// safetyContext holds state collected from earlier statements in the file.
+type safetyContext struct {
+	// created holds tables created within the file. They
+	// hold no data yet, so locking and backfill are not a concern.
+	created map[string]bool
+}
+
+var (
+	createTablePattern  = regexp.MustCompile(`^CREATE (?:TEMPORARY )?TABLE (?:IF NOT EXISTS )?([^\s(]+)`)
+	alterTablePattern   = regexp.MustCompile(`^ALTER TABLE (?:IF EXISTS )?(?:ONLY )?(\S+) (.*)$`)
+	createIndexPattern  = regexp.MustCompile(`^CREATE (?:UNIQUE )?INDEX (.*?)\bON (?:ONLY )?([^\s(]+)`)
+	alterTypePattern    = regexp.MustCompile(`^ALTER (?:COLUMN )?\S+ (?:SET DATA )?TYPE `)
+	modifyColumnPattern = regexp.MustCompile(`^(?:MODIFY|CHANGE) `)
+	wherePattern        = regexp.MustCompile(`\bWHERE\b`)
+)
+
+var safetyRules = []safetyRule{
+	{
+		id:       "drop-table",
+		severity: SeverityError,
+		message:  "dropping a table loses data and breaks application versions still using it",
+		match: func(query string, _ *safetyContext) bool {
+			return strings.HasPrefix(query, "DROP TABLE ")
+		},
+	},
+	{
+		id:       "truncate-table",
+		severity: SeverityError,
+		message:  "truncating a table loses data",
+		match: func(query string, _ *safetyContext) bool {
+			return strings.HasPrefix(query, "TRUNCATE ")
+		},
+	},
+	{
+		id:       "drop-column",
+		severity: SeverityError,
+		message:  "dropping a column loses data and breaks application versions still using it",
+		match: func(query string, _ *safetyContext) bool {
+			return hasAlterClause(query, func(clause string) bool {
+				fields := strings.Fields(clause)
+				if len(fields) < 2 || fields[0] != "DROP" {
+					return false
+				}
+				switch fields[1] {
+				case "INDEX", "KEY", "PRIMARY", "FOREIGN", "CONSTRAINT", "CHECK", "PARTITION":
+					return false
+				}
+				return true
+			})
+		},
+	},
+	{
+		id:       "not-null-without-default",
+		severity: SeverityError,
+		message:  "adding a NOT NULL column without a default fails on tables with rows, and breaks inserts from running application versions",
+		match: func(query string, file *safetyContext) bool {
+			if file.created[alterTable(query)] {
+				return false
+			}
+			return hasAlterClause(query, func(clause string) bool {
+				fields := strings.Fields(clause)
+				if len(fields) < 2 || fields[0] != "ADD" {
+					return false
+				}
+				switch fields[1] {
+				case "INDEX", "KEY", "PRIMARY", "UNIQUE", "FOREIGN", "CONSTRAINT", "CHECK", "FULLTEXT", "SPATIAL", "PARTITION":
+					return false
+				}
+				if !strings.Contains(clause, "NOT NULL") || strings.Contains(clause, "DEFAULT") {
+					return false
+				}
+				// values are generated for existing rows
+				for _, generated := range []string{"AUTO_INCREMENT", "SERIAL", "GENERATED", "IDENTITY"} {
+					if strings.Contains(clause, generated) {
+						return false
+					}
+				}
+				return true
+			})
+		},
+	},
+	{
+		id:       "change-column-type",
+		severity: SeverityWarning,
+		message:  "changing a column type may rewrite and lock the table, and may truncate existing values",
+		match: func(query string, file *safetyContext) bool {
+			if file.created[alterTable(query)] {
+				return false
+			}
+			return hasAlterClause(query, func(clause string) bool {
+				return alterTypePattern.MatchString(clause) || modifyColumnPattern.MatchString(clause)
+			})
+		},
+	},
+	{
+		id:       "rename",
+		severity: SeverityWarning,
+		message:  "renaming a table or column breaks application versions still using the old name",
+		match: func(query string, _ *safetyContext) bool {
+			if strings.HasPrefix(query, "RENAME TABLE ") {
+				return true
+			}
+			return hasAlterClause(query, func(clause string) bool {
+				fields := strings.Fields(clause)
+				if len(fields) < 2 || fields[0] != "RENAME" {
+					return false
+				}
+				switch fields[1] {
+				case "INDEX", "KEY", "CONSTRAINT":
+					return false
+				}
+				return true
+			})
+		},
+	},
+	{
+		id:       "index-without-concurrently",
+		severity: SeverityWarning,
+		message:  "creating an index without CONCURRENTLY blocks writes to the table until the index is built",
+		drivers:  []string{"postgres"},
+		match: func(query string, file *safetyContext) bool {
+			match := createIndexPattern.FindStringSubmatch(query)
+			if match == nil || strings.Contains(match[1], "CONCURRENTLY") {
+				return false
+			}
+			return !file.created[tableName(match[2])]
+		},
+	},
+	{
+		id:       "update-without-where",
+		severity: SeverityError,
+		message:  "UPDATE without WHERE modifies every row in the table",
+		match: func(query string, _ *safetyContext) bool {
+			return strings.HasPrefix(query, "UPDATE ") && !wherePattern.MatchString(topLevel(query))
+		},
+	},
+	{
+		id:       "delete-without-where",
+		severity: SeverityError,
+		message:  "DELETE without WHERE removes every row in the table",
+		match: func(query string, _ *safetyContext) bool {
+			return strings.HasPrefix(query, "DELETE ") && !wherePattern.MatchString(topLevel(query))
+		},
+	},
+}
+
+// tableName strips identifier quotes and the schema from a table name.
+func tableName(name string) string {
+	name = strings.Trim(name, "`\"[];")
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = strings.Trim(name[idx+1:], "`\"[]")
+	}
+	return strings.ToLower(name)
+}
+
+// alterTable returns the table name of an ALTER TABLE statement.
+func alterTable(query string) string {
+	match := alterTablePattern.FindStringSubmatch(query)
+	if match == nil {
+		return ""
+	}
+	return tableName(match[1])
+}
+
+// hasAlterClause returns true if the query is an ALTER TABLE
+// statement and any of the comma separated clauses match.
+func hasAlterClause(query string, match func(clause string) bool) bool {
+	alter := alterTablePattern.FindStringSubmatch(query)
+	if alter == nil {
+		return false
+	}
+	for _, clause := range splitClauses(alter[2]) {
+		if match(clause) {
+			return true
+		}
+	}
+	return false
+}
+
+// topLevel returns the query without the contents of string literals and
+// parentheses, so a WHERE in a subquery or a string isn't taken as a WHERE
+// of the statement.
+func topLevel(query string) string {
+	var (
+		sb     strings.Builder
+		depth  int
+		quoted bool
+	)
+	for _, c := range query {
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+			continue
+		case c == '(':
+			depth++
+			if depth > 1 {
+				continue
+			}
+		case c == ')':
+			depth = max(depth-1, 0)
+		}
+		if depth == 0 || (depth == 1 && c == '(') {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+// splitClauses splits a string on commas outside of parentheses.
+func splitClauses(s string) []string {
+	var (
+		result []string
+		depth  int
+		start  int
+	)
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				result = append(result, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(result, strings.TrimSpace(s[start:]))
+}
+
+// Analyze returns safety issues for statements in a migration file,
+// starting with the statement at index from. Statements with a
+// `-- mig:allow <rule>` directive are not reported for that rule.
+func Analyze(filename string, contents []byte, driverName string, from int) []*SafetyIssue {
+	if driverName == "pgx" {
+		driverName = "postgres"
+	}
+
+	// Invalid mig:if blocks are reported when the migrations run.
+	if filtered, err := forDriver(contents, driverName); err == nil {
+		contents = filtered
+	}
+
+	result := []*SafetyIssue{}
+	file := &safetyContext{
+		created: map[string]bool{},
+	}
+	for idx, stmt := range parse(contents).statements {
+		query := strings.ToUpper(strings.Join(strings.Fields(stmt.query), " "))
+		if match := createTablePattern.FindStringSubmatch(query); match != nil {
+			file.created[tableName(match[1])] = true
+		}
+		if idx < from {
+			continue
+		}
+
+		for _, rule := range safetyRules {
+			if len(rule.drivers) > 0 && !slices.Contains(rule.drivers, driverName) {
+				continue
+			}
+			if !rule.match(query, file) || stmt.allows(rule.id) {
+				continue
+			}
+			result = append(result, &SafetyIssue{
+				Filename: filename,
+				Line:     stmt.line,
+				Index:    idx,
+				Rule:     rule.id,
+				Severity: rule.severity,
+				Message:  rule.message,
+				Query:    stmt.query,
+			})
+		}
+	}
+	return result
+}
+
+// CheckSafety analyzes the statements that haven't been applied to the database yet.
+func CheckSafety(ctx context.Context, sqldb *sqlx.DB, fs FS, options *Options) ([]*SafetyIssue, error) {
+	files, err := Status(ctx, sqldb, fs, options)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*SafetyIssue{}
+	for _, file := range files {
+		if file.State == StateApplied {
+			continue
+		}
+		contents, err := fs.readFor(file.Filename, driverName(sqldb))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading %s: %w", file.Filename, err)
+		}
+		result = append(result, Analyze(file.Filename, contents, driverName(sqldb), file.Applied)...)
+	}
+	return result, nil
+}
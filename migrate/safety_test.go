@@ -0,0 +1,68 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze(t *testing.T) {
+	testcases := []struct {
+		driver string
+		query  string
+		rules  []string
+	}{
+		{"mysql", "DROP TABLE event;", []string{"drop-table"}},
+		{"mysql", "TRUNCATE TABLE event;", []string{"truncate-table"}},
+		{"mysql", "ALTER TABLE event DROP COLUMN status;", []string{"drop-column"}},
+		{"mysql", "ALTER TABLE `event` DROP `status`, ADD INDEX idx_kind (kind);", []string{"drop-column"}},
+		{"mysql", "ALTER TABLE event DROP INDEX idx_status;", nil},
+		{"postgres", "ALTER TABLE event ALTER COLUMN status DROP DEFAULT;", nil},
+		{"mysql", "ALTER TABLE event ADD COLUMN status varchar(32) NOT NULL;", []string{"not-null-without-default"}},
+		{"mysql", "ALTER TABLE event ADD COLUMN status varchar(32) NOT NULL DEFAULT 'new';", nil},
+		{"mysql", "ALTER TABLE event ADD COLUMN amount decimal(10,2) NULL;", nil},
+		{"postgres", "ALTER TABLE event ALTER COLUMN status TYPE text;", []string{"change-column-type"}},
+		{"mysql", "ALTER TABLE event MODIFY status text NOT NULL;", []string{"change-column-type"}},
+		{"postgres", "ALTER TABLE event RENAME COLUMN status TO state;", []string{"rename"}},
+		{"mysql", "RENAME TABLE event TO events;", []string{"rename"}},
+		{"mysql", "ALTER TABLE event RENAME INDEX a TO b;", nil},
+		{"postgres", "CREATE INDEX idx_event_status ON event (status);", []string{"index-without-concurrently"}},
+		{"postgres", "CREATE INDEX CONCURRENTLY idx_event_status ON event (status);", nil},
+		{"mysql", "CREATE INDEX idx_event_status ON event (status);", nil},
+		{"sqlite", "UPDATE event SET status='new';", []string{"update-without-where"}},
+		{"sqlite", "UPDATE event SET status='new' WHERE status IS NULL;", nil},
+		{"sqlite", "DELETE FROM event;", []string{"delete-without-where"}},
+		{"sqlite", "DELETE FROM event WHERE id IN (SELECT event_id FROM archive);", nil},
+		{"postgres", "DELETE FROM event USING archive WHERE event.id = archive.event_id;", nil},
+		{"postgres", "UPDATE event SET status = (SELECT status FROM defaults WHERE defaults.kind = 'event');", []string{"update-without-where"}},
+		{"postgres", "UPDATE event SET status = s.status FROM (SELECT status FROM defaults WHERE id = 1) s;", []string{"update-without-where"}},
+		{"postgres", "DELETE FROM event USING (SELECT id FROM archive WHERE archived) a;", []string{"delete-without-where"}},
+		{"sqlite", "UPDATE event SET note = 'where (it happened';", []string{"update-without-where"}},
+		{"sqlite", "UPDATE event SET note = 'it''s (here' WHERE id = 1;", nil},
+		{"sqlite", "DROP TABLE event; -- mig:allow drop-table", nil},
+		{"sqlite", "-- mig:allow update-without-where, drop-table\nUPDATE event SET status='new';", nil},
+		{"sqlite", "-- mig:allow drop-column\nDROP TABLE event;", []string{"drop-table"}},
+		{"postgres", "CREATE TABLE event (id int);\nCREATE INDEX idx_event_id ON event (id);\nALTER TABLE event ADD COLUMN status text NOT NULL;", nil},
+	}
+
+	for _, tc := range testcases {
+		issues := Analyze("test.up.sql", []byte(tc.query), tc.driver, 0)
+
+		rules := []string{}
+		for _, issue := range issues {
+			rules = append(rules, issue.Rule)
+		}
+		require.ElementsMatch(t, tc.rules, rules, "%s: %s", tc.driver, tc.query)
+	}
+}
+
+func TestAnalyzeFrom(t *testing.T) {
+	contents := []byte("DROP TABLE a;\n\n-- drop the second table\nDROP TABLE b;")
+
+	issues := Analyze("test.up.sql", contents, "sqlite", 1)
+	require.Len(t, issues, 1)
+	require.Equal(t, 1, issues[0].Index)
+	require.Equal(t, 4, issues[0].Line)
+	require.Equal(t, SeverityError, issues[0].Severity)
+	require.Equal(t, "DROP TABLE b", issues[0].Query)
+}
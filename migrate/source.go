@@ -0,0 +1,175 @@
+package migrate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// archiveExtensions are the archive formats accepted as a migrations path.
+var archiveExtensions = []string{".tar.gz", ".tgz", ".zip"}
+
+// IsArchive returns true if filename is a .tar.gz or .zip archive.
+func IsArchive(filename string) bool {
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(strings.ToLower(filename), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// openPath returns the file system holding the files of options.Path.
+// The path is a directory, a .tar.gz or .zip archive, or with GitRef
+// set, a directory in a git repository read at that revision.
+func openPath(options *Options) (fs.FS, error) {
+	switch {
+	case options.GitRef != "":
+		return openGit(options.Path, options.GitRef)
+	case IsArchive(options.Path):
+		return openArchive(options.Path)
+	}
+
+	if err := assertDir(options.Path); err != nil {
+		return nil, err
+	}
+	return os.DirFS(options.Path), nil
+}
+
+// openArchive reads a .tar.gz or .zip archive. If the files are in a
+// single top-level directory, e.g. `schema/`, the directory is the root.
+func openArchive(filename string) (fs.FS, error) {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("path: %w", err)
+	}
+
+	var fsys fs.FS
+	if strings.HasSuffix(strings.ToLower(filename), ".zip") {
+		fsys, err = zip.NewReader(bytes.NewReader(contents), int64(len(contents)))
+	} else {
+		fsys, err = readTarGz(bytes.NewReader(contents))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive %s: %w", filename, err)
+	}
+	return archiveRoot(fsys)
+}
+
+// openGit reads a directory from a git repository at a revision. The
+// directory doesn't need to exist in the working tree.
+func openGit(dir string, ref string) (fs.FS, error) {
+	// Resolve the directory in the repository from the nearest existing directory.
+	cwd, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	rest := ""
+	for {
+		if _, err := os.Stat(cwd); err == nil || filepath.Dir(cwd) == cwd {
+			break
+		}
+		cwd, rest = filepath.Dir(cwd), path.Join(filepath.Base(cwd), rest)
+	}
+
+	git := func(args ...string) ([]byte, error) {
+		var stderr bytes.Buffer
+		cmd := exec.Command("git", args...)
+		cmd.Dir = cwd
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s at %s: %w: %s", dir, ref, err, strings.TrimSpace(stderr.String()))
+		}
+		return out, nil
+	}
+
+	// git archive runs from the top of the repository.
+	out, err := git("rev-parse", "--show-prefix", "--show-cdup")
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(out), "\n")
+	prefix, cdup := lines[0], lines[1]
+	cwd = filepath.Join(cwd, cdup)
+
+	// The ref is resolved to a commit, so it can't be read as an option,
+	// or select something else than a revision.
+	out, err = git("rev-parse", "--verify", "--quiet", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %q isn't a commit, tag or branch", dir, ref)
+	}
+	commit := strings.TrimSpace(string(out))
+
+	tree := commit + "^{tree}"
+	if name := path.Join(prefix, rest); name != "" {
+		tree = commit + ":" + name
+	}
+
+	out, err = git("archive", "--format=tar", "--end-of-options", tree)
+	if err != nil {
+		return nil, err
+	}
+	return readTar(bytes.NewReader(out))
+}
+
+func readTarGz(r io.Reader) (fs.FS, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	return readTar(gz)
+}
+
+// readTar reads the regular files of a tar archive into memory.
+func readTar(r io.Reader) (fs.FS, error) {
+	result := memFS{}
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+
+		contents, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = contents
+	}
+}
+
+// archiveRoot returns the single top-level directory of fsys, if the
+// archive holds only that directory, or fsys.
+func archiveRoot(fsys fs.FS) (fs.FS, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return fs.Sub(fsys, entries[0].Name())
+	}
+	return fsys, nil
+}
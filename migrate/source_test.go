@@ -0,0 +1,152 @@
+package migrate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+var sourceFiles = map[string]string{
+	"1-users.up.sql":   "CREATE TABLE users (id integer);",
+	"1-users.down.sql": "DROP TABLE users;",
+	"2-posts.up.yaml":  "- create_table:\n    name: posts\n    columns:\n      - name: id\n        type: integer\n",
+	"README.md":        "not a migration",
+}
+
+func TestLoadArchive(t *testing.T) {
+	dir := t.TempDir()
+
+	var tarball bytes.Buffer
+	gz := gzip.NewWriter(&tarball)
+	tw := tar.NewWriter(gz)
+	for name, contents := range sourceFiles {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./" + name, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "schema.tar.gz"), tarball.Bytes(), 0644))
+
+	// Files in a single top-level directory
+	var zipball bytes.Buffer
+	zw := zip.NewWriter(&zipball)
+	for name, contents := range sourceFiles {
+		w, err := zw.Create("schema/" + name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "schema.zip"), zipball.Bytes(), 0644))
+
+	for _, filename := range []string{"schema.tar.gz", "schema.zip"} {
+		t.Run(filename, func(t *testing.T) {
+			options := &Options{Path: filepath.Join(dir, filename), Project: "archive"}
+			require.NoError(t, Load(options))
+			t.Cleanup(func() {
+				delete(migrations, "archive")
+			})
+
+			fs, err := Loaded("archive")
+			require.NoError(t, err)
+			require.Len(t, fs, 3)
+			require.Equal(t, []string{"1-users.up.sql", "2-posts.up.yaml"}, fs.Migrations())
+			require.Equal(t, sourceFiles["1-users.up.sql"], string(fs["1-users.up.sql"]))
+		})
+	}
+
+	err := Load(&Options{Path: filepath.Join(dir, "missing.zip"), Project: "archive"})
+	require.Error(t, err)
+}
+
+func TestLoadGitRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=mig", "-c", "user.email=mig@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(filename, contents string) {
+		filename = filepath.Join(dir, filename)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+		require.NoError(t, os.WriteFile(filename, []byte(contents), 0644))
+	}
+
+	git("init", "-q")
+	write("schema/app/1-users.up.sql", sourceFiles["1-users.up.sql"])
+	git("add", "-A")
+	git("commit", "-q", "-m", "users")
+	git("tag", "v1")
+
+	write("schema/app/2-posts.up.yaml", sourceFiles["2-posts.up.yaml"])
+	git("add", "-A")
+	git("commit", "-q", "-m", "posts")
+	write("schema/app/3-uncommitted.up.sql", "CREATE TABLE tags (id integer);")
+
+	load := func(ref string) []string {
+		options := &Options{Path: filepath.Join(dir, "schema/app"), Project: "git", GitRef: ref}
+		require.NoError(t, Load(options))
+		t.Cleanup(func() {
+			delete(migrations, "git")
+		})
+		fs, err := Loaded("git")
+		require.NoError(t, err)
+		return fs.Migrations()
+	}
+
+	require.Equal(t, []string{"1-users.up.sql"}, load("v1"))
+
+	// The directory doesn't need to exist in the working tree
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "schema/app")))
+	require.Equal(t, []string{"1-users.up.sql"}, load("v1"))
+	require.Equal(t, []string{"1-users.up.sql", "2-posts.up.yaml"}, load("HEAD"))
+
+	err := Load(&Options{Path: filepath.Join(dir, "schema/app"), Project: "git", GitRef: "missing"})
+	require.ErrorContains(t, err, "error reading")
+
+	// Refs are resolved to commits, and aren't read as options
+	output := filepath.Join(dir, "output.tar")
+	for _, ref := range []string{"--output=" + output, "HEAD:schema/app", "-v"} {
+		err := Load(&Options{Path: filepath.Join(dir, "schema/app"), Project: "git", GitRef: ref})
+		require.ErrorContains(t, err, "isn't a commit, tag or branch", ref)
+	}
+	require.NoFileExists(t, output)
+
+	// Projects for --all are read from the revision too
+	projects, err := LoadAll(&Options{Path: filepath.Join(dir, "schema"), GitRef: "v1"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"app"}, projects)
+}
+
+func TestMemFS(t *testing.T) {
+	fsys := memFS{
+		"1-users.up.sql":        []byte(sourceFiles["1-users.up.sql"]),
+		"schema/1-posts.up.sql": []byte("CREATE TABLE posts (id integer);"),
+		"schema/app/README.md":  []byte(sourceFiles["README.md"]),
+	}
+	require.NoError(t, fstest.TestFS(fsys, "1-users.up.sql", "schema/1-posts.up.sql", "schema/app/README.md"))
+
+	sub, err := fs.Sub(fsys, "schema")
+	require.NoError(t, err)
+	matches, err := fs.Glob(sub, "*.up.sql")
+	require.NoError(t, err)
+	require.Equal(t, []string{"1-posts.up.sql"}, matches)
+
+	_, err = fsys.Open("missing")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,177 @@
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Baseline is a migration file replacing older migration files.
+type Baseline struct {
+	Filename string
+	Contents []byte
+
+	// Replaces lists the replaced migration files.
+	Replaces []string
+}
+
+// Squash returns a baseline migration replacing the migration files in
+// fs before the file `before`. The baseline replays the statements of the
+// replaced files, in order, so constraints, defaults, views, triggers and
+// data inserted by the files are kept. It isn't generated from the
+// introspected schema, and doesn't collapse the history of the files.
+// Portable migrations are rendered for each driver, in `-- mig:if` blocks.
+//
+// The baseline lists the replaced files with `-- mig:replaces` directives.
+// On databases where the replaced files have been applied, the baseline
+// is recorded as applied without running it. The `mig:replaces` and
+// `mig:assert` directives of the replaced files aren't copied.
+func Squash(fs FS, before string) (*Baseline, error) {
+	files := fs.Migrations()
+	idx := slices.Index(files, before)
+	if idx < 0 {
+		return nil, fmt.Errorf("migration %s doesn't exist", before)
+	}
+	if idx == 0 {
+		return nil, fmt.Errorf("no migrations before %s", before)
+	}
+
+	replaced := files[:idx]
+	last := replaced[len(replaced)-1]
+	filename := strings.TrimSuffix(last, ".up"+filepath.Ext(last)) + "-baseline.up.sql"
+	if filename >= before {
+		return nil, fmt.Errorf("baseline %s doesn't sort before %s", filename, before)
+	}
+
+	var contents bytes.Buffer
+	fmt.Fprintf(&contents, "-- Baseline generated by mig squash, replaces the migrations before %s.\n", before)
+	for _, name := range replaced {
+		fmt.Fprintf(&contents, "-- mig:replaces %s\n", name)
+	}
+	for _, name := range replaced {
+		body, err := squashFile(fs, name)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", name, err)
+		}
+		fmt.Fprintf(&contents, "\n-- %s\n%s\n", name, body)
+	}
+
+	return &Baseline{
+		Filename: filename,
+		Contents: contents.Bytes(),
+		Replaces: replaced,
+	}, nil
+}
+
+// squashFile returns the statements of a replaced file for the baseline.
+// SQL files are copied, with their `-- mig:if` blocks. Portable files are
+// rendered for each driver in a `-- mig:if` block; drivers the file can't
+// be rendered for are left out, with the error as a comment.
+func squashFile(fs FS, filename string) (string, error) {
+	contents, err := fs.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	if !isPortable(filename) {
+		if _, err := forDriver(contents, ""); err != nil {
+			return "", err
+		}
+		return squashStatements(contents), nil
+	}
+
+	var result strings.Builder
+	for _, driver := range blockDrivers {
+		rendered, err := renderPortable(contents, driver)
+		if err != nil {
+			fmt.Fprintf(&result, "-- not rendered for %s: %s\n", driver, strings.ReplaceAll(err.Error(), "\n", " "))
+			continue
+		}
+		fmt.Fprintf(&result, "-- mig:if %s\n%s\n-- mig:end\n", driver, bytes.TrimSpace(rendered))
+	}
+	return strings.TrimSuffix(result.String(), "\n"), nil
+}
+
+// squashStatements removes the `mig:replaces` and `mig:assert` directives
+// from a migration file, and terminates the last statement with `;`, so
+// it isn't joined with the statements of the next file.
+func squashStatements(contents []byte) string {
+	lines := []string{}
+	for _, line := range strings.Split(string(contents), "\n") {
+		if loc := commentPattern.FindStringIndex(line); loc != nil {
+			match := directivePattern.FindStringSubmatch(strings.TrimSpace(line[loc[0]:]))
+			if match != nil && (match[1] == "replaces" || match[1] == "assert") {
+				line = line[:loc[0]]
+				if strings.TrimSpace(line) == "" {
+					continue
+				}
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	for idx := len(lines) - 1; idx >= 0; idx-- {
+		code, comment := lines[idx], ""
+		if loc := commentPattern.FindStringIndex(code); loc != nil {
+			code, comment = code[:loc[0]], code[loc[0]:]
+		}
+		code = strings.TrimRight(code, " \t\r")
+		if code == "" {
+			continue
+		}
+		if !strings.HasSuffix(code, ";") {
+			lines[idx] = code + ";" + comment
+		}
+		break
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// replacedFiles returns the files listed in `-- mig:replaces` directives.
+func replacedFiles(directives []directive) []string {
+	result := []string{}
+	for _, d := range directives {
+		if d.name == "replaces" && d.args != "" {
+			result = append(result, d.args)
+		}
+	}
+	return result
+}
+
+// replaced returns the files replaced by baselines in fs.
+func (fs FS) replaced() ([]string, error) {
+	result := []string{}
+	for _, filename := range fs.Migrations() {
+		contents, err := fs.readFor(filename, "")
+		if err != nil {
+			return nil, fmt.Errorf("Error reading %s: %w", filename, err)
+		}
+		result = append(result, replacedFiles(parse(contents).directives)...)
+	}
+	return result, nil
+}
+
+// replacedApplied returns true if the replaced files of a baseline are
+// recorded as applied. It returns false if none of them are recorded,
+// and an error if only some of them have been applied.
+func replacedApplied(filename string, replaced []string, records map[string]*Migration) (bool, error) {
+	recorded, missing := 0, []string{}
+	for _, name := range replaced {
+		record, ok := records[name]
+		if ok {
+			recorded++
+		}
+		if !ok || record.Status != StatusOK {
+			missing = append(missing, name)
+		}
+	}
+	if recorded == 0 {
+		return false, nil
+	}
+	if len(missing) > 0 {
+		return false, fmt.Errorf("%s replaces migrations that aren't fully applied, apply them from the archive first: %s", filename, strings.Join(missing, ", "))
+	}
+	return true, nil
+}
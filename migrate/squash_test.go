@@ -0,0 +1,178 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-bridget/mig/model"
+)
+
+// dump returns the schema and the rows of a sqlite database,
+// without the bookkeeping tables.
+func dump(t *testing.T, db *sqlx.DB) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	type object struct {
+		Type string `db:"type"`
+		Name string `db:"name"`
+		SQL  string `db:"sql"`
+	}
+	objects := []object{}
+	require.NoError(t, db.SelectContext(ctx, &objects, "SELECT type, name, COALESCE(sql, '') AS sql FROM sqlite_schema WHERE name NOT LIKE 'sqlite_%' ORDER BY name"))
+
+	result := []string{}
+	for _, o := range objects {
+		if IsBookkeeping(o.Name) {
+			continue
+		}
+		result = append(result, o.SQL)
+		if o.Type != "table" {
+			continue
+		}
+
+		rows, err := db.QueryxContext(ctx, "SELECT * FROM "+o.Name+" ORDER BY rowid")
+		require.NoError(t, err)
+		for rows.Next() {
+			row, err := rows.SliceScan()
+			require.NoError(t, err)
+			result = append(result, fmt.Sprintf("%s: %v", o.Name, row))
+		}
+		require.NoError(t, rows.Close())
+	}
+	return result
+}
+
+func TestSquash(t *testing.T) {
+	ctx := context.Background()
+	options := &Options{
+		Project: "test",
+		Apply:   true,
+	}
+
+	fs := FS{
+		"1-users.up.sql": []byte("CREATE TABLE users (\n  id integer,\n  email text,\n  PRIMARY KEY (id)\n);\nCREATE UNIQUE INDEX users_email ON users (email);"),
+		"2-posts.up.sql": []byte("CREATE TABLE posts (\n  id integer,\n  user_id integer\n);\nCREATE INDEX posts_user ON posts (user_id);"),
+		"3-tags.up.sql":  []byte("CREATE TABLE tags (\n  id integer\n);"),
+	}
+
+	_, err := Squash(fs, "1-users.up.sql")
+	require.ErrorContains(t, err, "no migrations before 1-users.up.sql")
+
+	baseline, err := Squash(fs, "3-tags.up.sql")
+	require.NoError(t, err)
+	require.Equal(t, "2-posts-baseline.up.sql", baseline.Filename)
+	require.Equal(t, []string{"1-users.up.sql", "2-posts.up.sql"}, baseline.Replaces)
+	require.Contains(t, string(baseline.Contents), "-- mig:replaces 1-users.up.sql\n-- mig:replaces 2-posts.up.sql\n")
+
+	squashed := FS{
+		baseline.Filename: baseline.Contents,
+		"3-tags.up.sql":   fs["3-tags.up.sql"],
+	}
+
+	// A database migrated with the replaced files records the baseline
+	migrated := newTestDB(t)
+	require.NoError(t, RunWithFS(ctx, migrated, fs, options))
+
+	pending, err := Pending(ctx, migrated, squashed, options)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	require.NoError(t, RunWithFS(ctx, migrated, squashed, options))
+	records, err := listMigrations(ctx, migrated, "test")
+	require.NoError(t, err)
+	require.Equal(t, StatusOK, records[baseline.Filename].Status)
+
+	// A new database runs the baseline, with the same schema
+	fresh := newTestDB(t)
+	require.NoError(t, RunWithFS(ctx, fresh, squashed, options))
+
+	want, err := snapshot(ctx, migrated)
+	require.NoError(t, err)
+	got, err := snapshot(ctx, fresh)
+	require.NoError(t, err)
+	diff := model.Compare(want, got)
+	require.True(t, diff.Empty(), diff.String())
+
+	// A database with some of the replaced files can't use the baseline
+	partial := newTestDB(t)
+	require.NoError(t, RunWithFS(ctx, partial, FS{"1-users.up.sql": fs["1-users.up.sql"]}, options))
+	err = RunWithFS(ctx, partial, squashed, options)
+	require.ErrorContains(t, err, "2-posts-baseline.up.sql replaces migrations that aren't fully applied, apply them from the archive first: 2-posts.up.sql")
+}
+
+func TestSquashDatabase(t *testing.T) {
+	ctx := context.Background()
+	options := &Options{
+		Project: "test",
+		Apply:   true,
+	}
+
+	// Constraints, defaults, foreign keys, views, triggers and data
+	// are kept by the baseline.
+	fs := FS{
+		"1-roles.up.sql": []byte(`CREATE TABLE roles (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  name text NOT NULL UNIQUE
+);
+-- mig:assert table roles exists
+INSERT INTO roles (name) VALUES ('admin'), ('member');`),
+		"2-users.up.yaml": []byte(`
+- create_table:
+    name: users
+    columns:
+      - name: id
+        type: integer
+      - name: email
+        type: text
+    indexes:
+      - primary: true
+        columns: [id]
+`),
+		"3-users-role.up.sql": []byte(`ALTER TABLE users ADD COLUMN role_id integer NOT NULL DEFAULT 2 REFERENCES roles (id);
+-- mig:if sqlite
+CREATE VIEW admins AS SELECT users.* FROM users WHERE role_id = 1;
+-- mig:end
+-- mig:if mysql, postgres
+CREATE VIEW admins AS SELECT users.* FROM users INNER JOIN roles ON roles.id = users.role_id WHERE roles.name = 'admin';
+-- mig:end
+CREATE TRIGGER roles_keep BEFORE DELETE ON roles BEGIN SELECT RAISE(ABORT, 'roles are kept'); END;
+INSERT INTO users (id, email) VALUES (1, 'admin@example.com')`),
+		"4-sessions.up.sql": []byte("CREATE TABLE sessions (id integer);"),
+	}
+
+	baseline, err := Squash(fs, "4-sessions.up.sql")
+	require.NoError(t, err)
+	require.Equal(t, "3-users-role-baseline.up.sql", baseline.Filename)
+
+	contents := string(baseline.Contents)
+	require.NotContains(t, contents, "mig:assert")
+	require.Contains(t, contents, "-- 2-users.up.yaml\n-- mig:if mysql\nCREATE TABLE `users`")
+	require.Contains(t, contents, "-- mig:if postgres\nCREATE TABLE \"users\"")
+	require.Contains(t, contents, "-- mig:if sqlite\nCREATE TABLE \"users\"")
+	require.Contains(t, contents, "INSERT INTO users (id, email) VALUES (1, 'admin@example.com');\n")
+
+	squashed := FS{
+		baseline.Filename:   baseline.Contents,
+		"4-sessions.up.sql": fs["4-sessions.up.sql"],
+	}
+
+	migrated := newTestDB(t)
+	require.NoError(t, RunWithFS(ctx, migrated, fs, options))
+	fresh := newTestDB(t)
+	require.NoError(t, RunWithFS(ctx, fresh, squashed, options))
+
+	want := dump(t, migrated)
+	require.Contains(t, want, "roles: [2 member]")
+	require.Equal(t, want, dump(t, fresh))
+
+	// The constraints are enforced on the new database
+	_, err = fresh.ExecContext(ctx, "INSERT INTO roles (name) VALUES (NULL)")
+	require.ErrorContains(t, err, "NOT NULL")
+	_, err = fresh.ExecContext(ctx, "DELETE FROM roles")
+	require.ErrorContains(t, err, "roles are kept")
+}
@@ -1,12 +1,68 @@
 package migrate
 
 import (
+	"bytes"
+	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/gofrs/uuid"
 )
 
+var (
+	// commentPattern matches sql comments ([whitespace]--*)
+	commentPattern = regexp.MustCompile(`\s*--.*`)
+
+	// directivePattern matches mig directives in comments (-- mig:name args)
+	directivePattern = regexp.MustCompile(`^--\s*mig:([\w-]+)\s*(.*)$`)
+)
+
+// directive is a `-- mig:name args` comment from a migration file.
+type directive struct {
+	name string
+	args string
+
+	// line is the 1-based line number of the directive.
+	line int
+}
+
+// statement is a single SQL statement from a migration file.
+type statement struct {
+	query string
+
+	// line is the 1-based line number where the statement starts.
+	line int
+
+	// directives are placed before or on the lines of the statement.
+	directives []directive
+}
+
+// allows returns true if the statement has a `-- mig:allow <rule>` directive for rule.
+func (s *statement) allows(rule string) bool {
+	for _, d := range s.directives {
+		if d.name != "allow" {
+			continue
+		}
+		for _, allowed := range strings.FieldsFunc(d.args, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		}) {
+			if allowed == rule || allowed == "all" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// script is a parsed migration file.
+type script struct {
+	statements []*statement
+
+	// directives holds all the directives in the file, in order.
+	directives []directive
+}
+
 func builtins(s string) string {
 	r := regexp.MustCompile(`(?i)uuid\(\)`)
 	s = r.ReplaceAllStringFunc(s, func(_ string) string {
@@ -16,23 +72,143 @@ func builtins(s string) string {
 	return s
 }
 
+// parse splits a migration file into statements. Statements are delimited
+// by a trailing `;` at the end of the line. SQL comments are removed from
+// the statements, while `-- mig:` directives are kept with the statement
+// they are placed on or before.
+func parse(contents []byte) *script {
+	result := &script{}
+
+	var (
+		current = &statement{}
+		lines   []string
+	)
+
+	flush := func() {
+		query := strings.TrimSpace(strings.Join(lines, "\n"))
+		if query != "" {
+			current.query = builtins(query)
+			result.statements = append(result.statements, current)
+			current = &statement{}
+		}
+		current.line = 0
+		lines = nil
+	}
+
+	for idx, line := range strings.Split(string(contents), "\n") {
+		// remove sql comments, keeping mig directives
+		if loc := commentPattern.FindStringIndex(line); loc != nil {
+			comment := strings.TrimSpace(line[loc[0]:])
+			if match := directivePattern.FindStringSubmatch(comment); match != nil {
+				d := directive{
+					name: match[1],
+					args: strings.TrimSpace(match[2]),
+					line: idx + 1,
+				}
+				current.directives = append(current.directives, d)
+				result.directives = append(result.directives, d)
+			}
+			line = line[:loc[0]]
+		}
+
+		if current.line == 0 && strings.TrimSpace(line) != "" {
+			current.line = idx + 1
+		}
+
+		// split statements by trailing ; at the end of the line
+		if strings.HasSuffix(line, ";") {
+			lines = append(lines, strings.TrimSuffix(line, ";"))
+			flush()
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+
+	return result
+}
+
+// blockDrivers are the driver names accepted by `-- mig:if`.
+var blockDrivers = []string{"mysql", "postgres", "sqlite"}
+
+// forDriver keeps the `-- mig:if <driver>` ... `-- mig:end` blocks of a
+// migration file that list the driver, and blanks the lines of other blocks.
+// Line numbers don't change, and statement indexes only depend on the
+// blocks for the driver, so they are stable per driver. An empty driver
+// keeps all blocks.
+func forDriver(contents []byte, driver string) ([]byte, error) {
+	if !bytes.Contains(contents, []byte("mig:if")) && !bytes.Contains(contents, []byte("mig:end")) {
+		return contents, nil
+	}
+	if driver == "pgx" {
+		driver = "postgres"
+	}
+
+	var (
+		block int // line of the open mig:if, 0 outside of blocks
+		skip  bool
+	)
+
+	lines := strings.Split(string(contents), "\n")
+	for idx, line := range lines {
+		loc := commentPattern.FindStringIndex(line)
+		if loc == nil {
+			if skip {
+				lines[idx] = ""
+			}
+			continue
+		}
+
+		match := directivePattern.FindStringSubmatch(strings.TrimSpace(line[loc[0]:]))
+		if match == nil || (match[1] != "if" && match[1] != "end") {
+			if skip {
+				lines[idx] = ""
+			}
+			continue
+		}
+		if strings.TrimSpace(line[:loc[0]]) != "" {
+			return nil, fmt.Errorf("mig:%s on line %d must be on its own line", match[1], idx+1)
+		}
+
+		switch match[1] {
+		case "if":
+			if block > 0 {
+				return nil, fmt.Errorf("mig:if on line %d is nested in mig:if on line %d", idx+1, block)
+			}
+			names := strings.FieldsFunc(match[2], func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			})
+			if len(names) == 0 {
+				return nil, fmt.Errorf("mig:if on line %d is missing a driver", idx+1)
+			}
+			for _, name := range names {
+				if !slices.Contains(blockDrivers, name) {
+					return nil, fmt.Errorf("mig:if on line %d has unknown driver %q", idx+1, name)
+				}
+			}
+			block, skip = idx+1, driver != "" && !slices.Contains(names, driver)
+		case "end":
+			if block == 0 {
+				return nil, fmt.Errorf("mig:end on line %d without mig:if", idx+1)
+			}
+			block, skip = 0, false
+		}
+		lines[idx] = ""
+	}
+	if block > 0 {
+		return nil, fmt.Errorf("mig:if on line %d is missing mig:end", block)
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
 func statements(contents []byte, err error) ([]string, error) {
 	result := []string{}
 	if err != nil {
 		return result, err
 	}
 
-	// remove sql comments from anywhere ([whitespace]--*\n)
-	comments := regexp.MustCompile(`\s*--.*`)
-	contents = comments.ReplaceAll(contents, nil)
-
-	// split statements by trailing ; at the end of the line
-	stmts := regexp.MustCompile(`(?m);$`).Split(string(contents), -1)
-	for _, stmt := range stmts {
-		stmt = strings.TrimSpace(stmt)
-		if stmt != "" {
-			result = append(result, builtins(stmt))
-		}
+	for _, stmt := range parse(contents).statements {
+		result = append(result, stmt.query)
 	}
 
 	return result, nil
@@ -68,3 +68,103 @@ func TestStatementsAppended(t *testing.T) {
 	require.Equal(t, "ok", status.Status)
 	require.Equal(t, 3, status.StatementIndex)
 }
+
+func TestStatementsDirectives(t *testing.T) {
+	contents := []byte(`-- mig:allow drop-table
+DROP TABLE a;
+
+DROP TABLE b; -- mig:allow drop-table, rename
+-- a regular comment
+UPDATE c SET d=1;`)
+
+	script := parse(contents)
+	require.Len(t, script.statements, 3)
+	require.Len(t, script.directives, 2)
+
+	require.Equal(t, "DROP TABLE a", script.statements[0].query)
+	require.Equal(t, 2, script.statements[0].line)
+	require.True(t, script.statements[0].allows("drop-table"))
+
+	require.Equal(t, 4, script.statements[1].line)
+	require.True(t, script.statements[1].allows("rename"))
+
+	require.Equal(t, 6, script.statements[2].line)
+	require.Empty(t, script.statements[2].directives)
+}
+
+func TestStatementsForDriver(t *testing.T) {
+	contents := []byte(`CREATE TABLE a (id integer);
+-- mig:if postgres
+CREATE INDEX CONCURRENTLY a_id ON a (id);
+-- mig:end
+-- mig:if mysql, sqlite
+CREATE INDEX a_id ON a (id);
+-- mig:end
+DROP TABLE b;`)
+
+	for driver, want := range map[string]string{
+		"postgres": "CREATE INDEX CONCURRENTLY a_id ON a (id)",
+		"pgx":      "CREATE INDEX CONCURRENTLY a_id ON a (id)",
+		"sqlite":   "CREATE INDEX a_id ON a (id)",
+	} {
+		filtered, err := forDriver(contents, driver)
+		require.NoError(t, err)
+
+		script := parse(filtered)
+		require.Len(t, script.statements, 3, driver)
+		require.Equal(t, want, script.statements[1].query, driver)
+		require.Equal(t, 8, script.statements[2].line, driver)
+		require.Empty(t, script.directives, driver)
+	}
+
+	// All blocks are kept without a driver
+	filtered, err := forDriver(contents, "")
+	require.NoError(t, err)
+	require.Len(t, parse(filtered).statements, 4)
+
+	for _, tc := range []struct {
+		contents string
+		err      string
+	}{
+		{"-- mig:if sqlite\nSELECT 1;", "mig:if on line 1 is missing mig:end"},
+		{"SELECT 1;\n-- mig:end", "mig:end on line 2 without mig:if"},
+		{"-- mig:if sqlite\n-- mig:if mysql\n-- mig:end", "mig:if on line 2 is nested in mig:if on line 1"},
+		{"-- mig:if oracle\n-- mig:end", `mig:if on line 1 has unknown driver "oracle"`},
+		{"-- mig:if\n-- mig:end", "mig:if on line 1 is missing a driver"},
+		{"SELECT 1; -- mig:if sqlite\n-- mig:end", "mig:if on line 1 must be on its own line"},
+	} {
+		_, err := forDriver([]byte(tc.contents), "sqlite")
+		require.EqualError(t, err, tc.err)
+	}
+}
+
+func TestRunForDriver(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	options := &Options{
+		Project: "test",
+		Apply:   true,
+	}
+
+	fs := FS{
+		"1-users.up.sql": []byte(`CREATE TABLE users (id integer, name text);
+-- mig:if postgres
+CREATE INDEX CONCURRENTLY users_name ON users (name);
+-- mig:end
+-- mig:if sqlite
+CREATE INDEX users_name ON users (name);
+CREATE INDEX users_id ON users (id);
+-- mig:end`),
+	}
+	require.NoError(t, RunWithFS(ctx, db, fs, options))
+
+	files, err := Status(ctx, db, fs, options)
+	require.NoError(t, err)
+	require.Equal(t, StateApplied, files[0].State)
+	require.Equal(t, 3, files[0].Statements)
+	require.Equal(t, 3, files[0].Applied)
+
+	var count int
+	require.NoError(t, db.GetContext(ctx, &count, "select count(*) from sqlite_schema where type='index' and tbl_name='users'"))
+	require.Equal(t, 2, count)
+}
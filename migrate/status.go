@@ -0,0 +1,194 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migration file states reported by Status.
+const (
+	// StateApplied means all statements in the file have been applied.
+	StateApplied = "applied"
+
+	// StatePending means the file, or statements appended to it, haven't been applied.
+	StatePending = "pending"
+
+	// StatePartial means a run was interrupted while applying the file.
+	StatePartial = "partial"
+
+	// StateFailed means a statement from the file failed to apply.
+	StateFailed = "failed"
+
+	// StateAhead means the file is recorded in the database, but it's
+	// missing from the migrations, e.g. when an older release runs
+	// against a database migrated by a newer release.
+	StateAhead = "ahead"
+)
+
+// FileStatus describes the state of a migration file in the database.
+type FileStatus struct {
+	Filename string
+
+	// State is one of the State* constants.
+	State string
+
+	// Statements is the number of statements in the migration file.
+	Statements int
+
+	// Applied is the number of statements recorded as applied.
+	Applied int
+
+	// Error holds the error message for failed migrations.
+	Error string
+}
+
+// Status returns the state of each migration file in fs for the project in options.
+// It doesn't modify the database; if the migrations table doesn't exist yet,
+// all files are reported as pending.
+func Status(ctx context.Context, sqldb *sqlx.DB, fs FS, options *Options) ([]*FileStatus, error) {
+	records, err := listMigrations(ctx, sqldb, options.Project)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*FileStatus{}
+	for _, filename := range fs.Migrations() {
+		contents, err := fs.readFor(filename, driverName(sqldb))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading %s: %w", filename, err)
+		}
+		script := parse(contents)
+
+		file := &FileStatus{
+			Filename:   filename,
+			State:      StatePending,
+			Statements: len(script.statements),
+		}
+		result = append(result, file)
+
+		record, ok := records[filename]
+		if !ok {
+			// A baseline is applied by recording it, if the files it replaces are applied.
+			if applied, _ := replacedApplied(filename, replacedFiles(script.directives), records); applied {
+				file.State = StateApplied
+				file.Applied = file.Statements
+			}
+			continue
+		}
+
+		file.Applied = record.StatementIndex + 1
+		switch record.Status {
+		case StatusOK:
+			if file.Applied >= file.Statements {
+				file.State = StateApplied
+			}
+		case StatusRunning:
+			file.State = StatePartial
+		default:
+			file.State = StateFailed
+			file.Error = record.Status
+		}
+	}
+	return result, nil
+}
+
+// listMigrations returns the recorded migrations for a project, keyed by filename.
+func listMigrations(ctx context.Context, sqldb *sqlx.DB, project string) (map[string]*Migration, error) {
+	result := map[string]*Migration{}
+
+	exists, err := tableExists(ctx, sqldb, "migrations")
+	if err != nil || !exists {
+		return result, err
+	}
+
+	rows := []*Migration{}
+	query := sqldb.Rebind("select * from migrations where project=? order by filename")
+	if err := sqldb.SelectContext(ctx, &rows, query, project); err != nil {
+		return nil, fmt.Errorf("error listing migrations: %w", err)
+	}
+	for _, row := range rows {
+		result[row.Filename] = row
+	}
+	return result, nil
+}
+
+// tableExists checks if a table exists in the current database or schema.
+func tableExists(ctx context.Context, sqldb *sqlx.DB, table string) (bool, error) {
+	var query string
+	switch driverName(sqldb) {
+	case "postgres":
+		query = "select count(*) from pg_class where relkind='r' and relname=? and relnamespace=(select oid from pg_namespace where nspname=current_schema())"
+	case "mysql":
+		query = "select count(*) from information_schema.tables where table_schema=DATABASE() and table_name=?"
+	case "sqlite":
+		query = "select count(*) from sqlite_schema where type='table' and name=?"
+	default:
+		return false, fmt.Errorf("unsupported driver: %s", sqldb.DriverName())
+	}
+
+	var count int
+	if err := sqldb.GetContext(ctx, &count, sqldb.Rebind(query), table); err != nil {
+		return false, fmt.Errorf("error checking %s table: %w", table, err)
+	}
+	return count > 0, nil
+}
+
+// Pending returns the migration files that need to be applied or fixed
+// before an application can use the database: pending, partially applied
+// and failed files from fs, and files recorded in the database that are
+// missing from fs, reported with StateAhead unless a baseline replaces
+// them. An empty result means the database is up to date with fs.
+func Pending(ctx context.Context, sqldb *sqlx.DB, fs FS, options *Options) ([]*FileStatus, error) {
+	files, err := Status(ctx, sqldb, fs, options)
+	if err != nil {
+		return nil, err
+	}
+
+	replaced, err := fs.replaced()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*FileStatus{}
+	known := map[string]bool{}
+	for _, file := range files {
+		known[file.Filename] = true
+		if file.State != StateApplied {
+			result = append(result, file)
+		}
+	}
+	// Files replaced by a baseline aren't ahead.
+	for _, filename := range replaced {
+		known[filename] = true
+	}
+
+	records, err := listMigrations(ctx, sqldb, options.Project)
+	if err != nil {
+		return nil, err
+	}
+
+	ahead := []*FileStatus{}
+	for filename, record := range records {
+		if known[filename] {
+			continue
+		}
+		file := &FileStatus{
+			Filename:   filename,
+			State:      StateAhead,
+			Statements: record.StatementIndex + 1,
+			Applied:    record.StatementIndex + 1,
+		}
+		if record.Status != StatusOK {
+			file.Error = record.Status
+		}
+		ahead = append(ahead, file)
+	}
+	sort.Slice(ahead, func(i, j int) bool {
+		return ahead[i].Filename < ahead[j].Filename
+	})
+
+	return append(result, ahead...), nil
+}
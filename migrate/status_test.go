@@ -0,0 +1,108 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	options := &Options{
+		Project: "test",
+		Apply:   true,
+	}
+
+	initial, err := testdataFS.ReadFile("testdata/pulse.up.sql")
+	require.NoError(t, err)
+
+	fs := FS{
+		"1-pulse.up.sql":  initial,
+		"2-broken.up.sql": []byte("CREATE TABLE broken (id integer);\nCREATE TABLE broken (id integer);"),
+	}
+
+	// No migrations table yet, everything is pending
+	files, err := Status(ctx, db, fs, options)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.Equal(t, StatePending, files[0].State)
+	require.Equal(t, 3, files[0].Statements)
+	require.Equal(t, 0, files[0].Applied)
+
+	require.Error(t, RunWithFS(ctx, db, fs, options))
+
+	files, err = Status(ctx, db, fs, options)
+	require.NoError(t, err)
+	require.Equal(t, StateApplied, files[0].State)
+	require.Equal(t, 3, files[0].Applied)
+	require.Equal(t, StateFailed, files[1].State)
+	require.Equal(t, 1, files[1].Applied)
+	require.Contains(t, files[1].Error, "already exists")
+
+	// Appended statements are pending
+	appended, err := testdataFS.ReadFile("testdata/pulse_appended.up.sql")
+	require.NoError(t, err)
+	fs["1-pulse.up.sql"] = appended
+
+	files, err = Status(ctx, db, fs, options)
+	require.NoError(t, err)
+	require.Equal(t, StatePending, files[0].State)
+	require.Equal(t, 3, files[0].Applied)
+	require.Equal(t, 4, files[0].Statements)
+
+	// Interrupted runs are partial
+	_, err = db.ExecContext(ctx, "UPDATE migrations SET status=? WHERE filename=?", StatusRunning, "2-broken.up.sql")
+	require.NoError(t, err)
+
+	files, err = Status(ctx, db, fs, options)
+	require.NoError(t, err)
+	require.Equal(t, StatePartial, files[1].State)
+}
+
+func TestPending(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	options := &Options{
+		Project: "test",
+		Apply:   true,
+	}
+
+	fs := FS{
+		"1-users.up.sql":  []byte("CREATE TABLE users (id integer);"),
+		"2-events.up.sql": []byte("CREATE TABLE events (id integer);"),
+	}
+
+	files, err := Pending(ctx, db, fs, options)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.Equal(t, StatePending, files[0].State)
+
+	require.NoError(t, RunWithFS(ctx, db, fs, options))
+
+	files, err = Pending(ctx, db, fs, options)
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	// An older release doesn't know about the second file
+	files, err = Pending(ctx, db, FS{"1-users.up.sql": fs["1-users.up.sql"]}, options)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, "2-events.up.sql", files[0].Filename)
+	require.Equal(t, StateAhead, files[0].State)
+
+	// A newer release adds a file
+	fs["3-broken.up.sql"] = []byte("CREATE TABLE users (id integer);")
+	files, err = Pending(ctx, db, fs, options)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, StatePending, files[0].State)
+
+	require.Error(t, RunWithFS(ctx, db, fs, options))
+
+	files, err = Pending(ctx, db, fs, options)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, StateFailed, files[0].State)
+}
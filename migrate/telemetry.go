@@ -0,0 +1,134 @@
+package migrate
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the tracer and meter of the package.
+const instrumentationName = "github.com/go-bridget/mig/migrate"
+
+// durationBuckets are histogram bucket boundaries in seconds,
+// from fast statements up to long running table rewrites.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900, 3600}
+
+// Span and metric attributes.
+const (
+	attrProject   = attribute.Key("mig.project")
+	attrFile      = attribute.Key("mig.file")
+	attrIndex     = attribute.Key("mig.statement.index")
+	attrStatus    = attribute.Key("mig.status")
+	attrDBSystem  = attribute.Key("db.system")
+	attrStatement = attribute.Key("db.statement")
+)
+
+// telemetry holds the tracer and the metric instruments for a run.
+type telemetry struct {
+	tracer trace.Tracer
+
+	statementsApplied metric.Int64Counter
+	statementsFailed  metric.Int64Counter
+	statementDuration metric.Float64Histogram
+	lockWait          metric.Float64Histogram
+}
+
+// newTelemetry returns the tracer and the metric instruments from the
+// providers in options. Without providers, the global OpenTelemetry
+// providers are used, which are no-ops until the application configures them.
+func newTelemetry(options *Options) *telemetry {
+	tracerProvider := options.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+	meterProvider := options.MeterProvider
+	if meterProvider == nil {
+		meterProvider = otel.GetMeterProvider()
+	}
+
+	meter := meterProvider.Meter(instrumentationName)
+	t := &telemetry{
+		tracer: tracerProvider.Tracer(instrumentationName),
+	}
+
+	// Instrument errors are reported by the SDK, and return no-op instruments.
+	t.statementsApplied, _ = meter.Int64Counter("mig.statements.applied",
+		metric.WithDescription("Number of applied migration statements"))
+
+	t.statementsFailed, _ = meter.Int64Counter("mig.statements.failed",
+		metric.WithDescription("Number of failed migration statements"))
+
+	t.statementDuration, _ = meter.Float64Histogram("mig.statement.duration",
+		metric.WithDescription("Execution time of migration statements"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...))
+
+	t.lockWait, _ = meter.Float64Histogram("mig.lock.wait",
+		metric.WithDescription("Time spent waiting for the migration lock"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...))
+
+	return t
+}
+
+// startSpan starts a span, and returns a function to end it. The span
+// status is set from the error passed to the returned function.
+func (r *runner) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, func(error)) {
+	ctx, span := r.telemetry.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// statementAttributes returns the span attributes of a statement. The
+// query is only included with TraceStatements, as it may hold data from
+// seed or data migrations.
+func (r *runner) statementAttributes(filename string, idx int, query string) []attribute.KeyValue {
+	result := []attribute.KeyValue{
+		attrFile.String(filename),
+		attrIndex.Int(idx),
+		attrDBSystem.String(r.driver),
+	}
+	if r.options.TraceStatements {
+		result = append(result, attrStatement.String(query))
+	}
+	return result
+}
+
+// recordStatement records the statement metrics. The statements
+// creating the bookkeeping tables aren't recorded.
+func (r *runner) recordStatement(ctx context.Context, filename string, duration time.Duration, err error) {
+	if filename == r.migrationsFile() {
+		return
+	}
+
+	attrs := metric.WithAttributes(
+		attrProject.String(r.options.Project),
+		attrFile.String(filename),
+		attrDBSystem.String(r.driver),
+	)
+
+	r.telemetry.statementDuration.Record(ctx, duration.Seconds(), attrs)
+	if err != nil {
+		r.telemetry.statementsFailed.Add(ctx, 1, attrs)
+		return
+	}
+	r.telemetry.statementsApplied.Add(ctx, 1, attrs)
+}
+
+// recordLockWait records the time spent acquiring the migration lock.
+func (r *runner) recordLockWait(ctx context.Context, duration time.Duration) {
+	r.telemetry.lockWait.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attrProject.String(r.options.Project),
+		attrDBSystem.String(r.driver),
+	))
+}
@@ -0,0 +1,93 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTelemetry(t *testing.T) {
+	ctx := context.Background()
+
+	spans := tracetest.NewInMemoryExporter()
+	reader := sdkmetric.NewManualReader()
+
+	fs := FS{
+		"1-users.up.sql":  []byte("CREATE TABLE users (id integer);\nCREATE INDEX users_id ON users (id);"),
+		"2-broken.up.sql": []byte("CREATE TABLE broken (id integer);\nCREATE TABLE broken (id integer);"),
+	}
+	err := RunWithFS(ctx, newTestDB(t), fs, &Options{
+		Project:        "test",
+		Apply:          true,
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)),
+		MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	})
+	require.Error(t, err)
+
+	names := map[string]int{}
+	for _, span := range spans.GetSpans() {
+		names[span.Name]++
+		for _, attr := range span.Attributes {
+			require.NotEqual(t, attrStatement, attr.Key, "statements are traced only with TraceStatements")
+		}
+	}
+	require.Equal(t, 1, names["mig.run"])
+	require.Equal(t, 2, names["mig.file"])
+	require.GreaterOrEqual(t, names["mig.statement"], 4)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+
+	counters := map[string]int64{}
+	histograms := map[string]uint64{}
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					file, _ := dp.Attributes.Value(attrFile)
+					require.NotEqual(t, "migrations-sqlite.sql", file.AsString())
+					counters[m.Name] += dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					histograms[m.Name] += dp.Count
+				}
+			}
+		}
+	}
+	require.Equal(t, int64(3), counters["mig.statements.applied"])
+	require.Equal(t, int64(1), counters["mig.statements.failed"])
+	require.NotZero(t, histograms["mig.statement.duration"])
+	require.Equal(t, uint64(2), histograms["mig.lock.wait"])
+}
+
+func TestTelemetryStatements(t *testing.T) {
+	spans := tracetest.NewInMemoryExporter()
+
+	fs := FS{
+		"1-users.up.sql": []byte("CREATE TABLE users (id integer);"),
+	}
+	err := RunWithFS(context.Background(), newTestDB(t), fs, &Options{
+		Project:         "test",
+		Apply:           true,
+		TraceStatements: true,
+		TracerProvider:  sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)),
+	})
+	require.NoError(t, err)
+
+	var statements []string
+	for _, span := range spans.GetSpans() {
+		for _, attr := range span.Attributes {
+			if attr.Key == attrStatement {
+				statements = append(statements, attr.Value.AsString())
+			}
+		}
+	}
+	require.Contains(t, statements, "CREATE TABLE users (id integer)")
+}
@@ -0,0 +1,43 @@
+package migrate
+
+import (
+	"sync"
+	"time"
+)
+
+// StatementTiming is the execution time of a statement from a migration file.
+type StatementTiming struct {
+	Project  string
+	Filename string
+
+	// Index is the index of the statement in the file.
+	Index int
+	Query string
+
+	Duration time.Duration
+	Err      error
+}
+
+// Timings collects statement timings from migration runs.
+// It's safe for concurrent use.
+type Timings struct {
+	mu         sync.Mutex
+	statements []*StatementTiming
+}
+
+// add records a statement timing. It's a no-op for nil Timings.
+func (t *Timings) add(timing *StatementTiming) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.statements = append(t.statements, timing)
+}
+
+// Statements returns the recorded statement timings in execution order.
+func (t *Timings) Statements() []*StatementTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*StatementTiming{}, t.statements...)
+}
@@ -0,0 +1,154 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/go-bridget/mig/db"
+	"github.com/go-bridget/mig/db/introspect"
+	"github.com/go-bridget/mig/model"
+)
+
+// Verify checks that the down migrations reverse the up migrations. For
+// each pending migration file, it applies the file, runs the down migration,
+// checks the schema equals the schema before the file was applied, and
+// applies the file again. Files that have already been applied are skipped.
+//
+// Verify leaves the database with all migration files applied.
+func Verify(ctx context.Context, sqldb *sqlx.DB, fs FS, options *Options) error {
+	files, err := Status(ctx, sqldb, fs, options)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		filename := file.Filename
+		if file.State == StateApplied {
+			options.logger().Println(filename, "SKIPPED (already applied)")
+			continue
+		}
+
+		down, ok := fs.Down(filename)
+		if !ok {
+			return fmt.Errorf("%s: missing down migration %s", filename, down)
+		}
+
+		// Apply only the current file and the hooks, the previous files have been applied.
+		up := fs.Hooks()
+		up[filename] = fs[filename]
+
+		before, err := snapshot(ctx, sqldb)
+		if err != nil {
+			return err
+		}
+
+		if err := RunWithFS(ctx, sqldb, up, options); err != nil {
+			return err
+		}
+
+		after, err := snapshot(ctx, sqldb)
+		if err != nil {
+			return err
+		}
+
+		if err := revert(ctx, sqldb, fs, filename, options); err != nil {
+			return err
+		}
+
+		reverted, err := snapshot(ctx, sqldb)
+		if err != nil {
+			return err
+		}
+		if diff := model.Compare(before, reverted); !diff.Empty() {
+			return fmt.Errorf("%s: schema differs after running %s:\n%s", filename, down, diff)
+		}
+
+		if err := RunWithFS(ctx, sqldb, up, options); err != nil {
+			return fmt.Errorf("%s: error applying after %s: %w", filename, down, err)
+		}
+
+		reapplied, err := snapshot(ctx, sqldb)
+		if err != nil {
+			return err
+		}
+		if diff := model.Compare(after, reapplied); !diff.Empty() {
+			return fmt.Errorf("%s: schema differs after applying the file again:\n%s", filename, diff)
+		}
+
+		options.logger().Println(filename, "VERIFIED")
+	}
+	return nil
+}
+
+// revert runs the down migration for an applied migration file,
+// and removes the file from the migrations table.
+func revert(ctx context.Context, sqldb *sqlx.DB, fs FS, filename string, options *Options) error {
+	down, _ := fs.Down(filename)
+	stmts, err := statements(fs.readFor(down, driverName(sqldb)))
+	if err != nil {
+		return fmt.Errorf("Error reading %s: %w", down, err)
+	}
+
+	r := newRunner(sqldb, fs, options)
+
+	release, err := r.open(ctx, sqldb)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Only the timeouts apply to down migrations. The init statements
+	// and the before hook ran when the file was applied, and they
+	// aren't expected to be idempotent.
+	if err := db.SetSessionTimeouts(ctx, r.conn, r.driver, options.StatementTimeout, options.LockTimeout); err != nil {
+		return err
+	}
+
+	tx, err := r.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.lock(ctx, tx, filename); err != nil {
+		return err
+	}
+
+	for idx, stmt := range stmts {
+		if err := r.exec(ctx, tx, down, idx, stmt); err != nil {
+			return fmt.Errorf("%s: statement %d failed: %w", down, idx+1, err)
+		}
+	}
+
+	query := tx.Rebind("delete from migrations where project=? and filename=?")
+	if _, err := tx.ExecContext(ctx, query, options.Project, filename); err != nil {
+		return fmt.Errorf("updating migration state failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	options.logger().Println(down, "OK")
+	return nil
+}
+
+// snapshot returns the database schema, excluding the bookkeeping tables.
+func snapshot(ctx context.Context, sqldb *sqlx.DB) ([]*model.Table, error) {
+	describer, err := introspect.NewDescriber(sqldb)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := introspect.ListTablesWithColumns(ctx, sqldb, describer)
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema: %w", err)
+	}
+
+	return slices.DeleteFunc(tables, func(table *model.Table) bool {
+		return IsBookkeeping(table.Name)
+	}), nil
+}
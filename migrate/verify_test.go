@@ -0,0 +1,77 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	options := &Options{
+		Project: "test",
+		Apply:   true,
+	}
+
+	fs := FS{
+		"1-users.up.sql":    []byte("CREATE TABLE users (id integer primary key, name text);\nCREATE INDEX users_name ON users (name);"),
+		"1-users.down.sql":  []byte("DROP TABLE users;"),
+		"2-email.up.sql":    []byte("ALTER TABLE users ADD COLUMN email text;"),
+		"2-email.down.sql":  []byte("ALTER TABLE users DROP COLUMN email;"),
+		"3-events.up.sql":   []byte("CREATE TABLE events (id integer, user_id integer);\nCREATE INDEX events_user ON events (user_id);"),
+		"3-events.down.sql": []byte("DROP TABLE events;"),
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		db := newTestDB(t)
+		require.NoError(t, Verify(ctx, db, fs, options))
+
+		files, err := Status(ctx, db, fs, options)
+		require.NoError(t, err)
+		for _, file := range files {
+			require.Equal(t, StateApplied, file.State, file.Filename)
+		}
+
+		// Applied files are skipped
+		require.NoError(t, Verify(ctx, db, fs, options))
+	})
+
+	t.Run("incomplete down", func(t *testing.T) {
+		broken := FS{}
+		for filename, contents := range fs {
+			broken[filename] = contents
+		}
+		broken["3-events.down.sql"] = []byte("DROP INDEX events_user;")
+
+		err := Verify(ctx, newTestDB(t), broken, options)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "3-events.up.sql: schema differs")
+		require.Contains(t, err.Error(), "+ table events")
+	})
+
+	t.Run("hooks", func(t *testing.T) {
+		db := newTestDB(t)
+		_, err := db.ExecContext(ctx, "CREATE TABLE hook_runs (name text)")
+		require.NoError(t, err)
+
+		hooked := FS{
+			BeforeHook:         []byte("INSERT INTO hook_runs VALUES ('before');"),
+			"1-users.up.sql":   fs["1-users.up.sql"],
+			"1-users.down.sql": fs["1-users.down.sql"],
+		}
+		require.NoError(t, Verify(ctx, db, hooked, options))
+
+		// The hook runs when the file is applied, not for the down migration
+		var runs int
+		require.NoError(t, db.GetContext(ctx, &runs, "SELECT count(*) FROM hook_runs"))
+		require.Equal(t, 2, runs)
+	})
+
+	t.Run("missing down", func(t *testing.T) {
+		missing := FS{"1-users.up.sql": fs["1-users.up.sql"]}
+
+		err := Verify(ctx, newTestDB(t), missing, options)
+		require.ErrorContains(t, err, "missing down migration 1-users.down.sql")
+	})
+}
@@ -0,0 +1,294 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Diff holds the differences between two schemas.
+type Diff struct {
+	// Added holds tables that only exist in the target schema.
+	Added []*Table `json:"added,omitempty" yaml:"added,omitempty"`
+
+	// Removed holds tables that only exist in the source schema.
+	Removed []*Table `json:"removed,omitempty" yaml:"removed,omitempty"`
+
+	// Changed holds tables that exist in both schemas with differences.
+	Changed []*TableDiff `json:"changed,omitempty" yaml:"changed,omitempty"`
+}
+
+// TableDiff holds the differences of a table between two schemas.
+type TableDiff struct {
+	Name string `json:"name" yaml:"name"`
+
+	From *Table `json:"-" yaml:"-"`
+	To   *Table `json:"-" yaml:"-"`
+
+	// Changes lists the changed table fields (comment).
+	Changes []Change `json:"changes,omitempty" yaml:"changes,omitempty"`
+
+	AddedColumns   []*Column     `json:"added_columns,omitempty" yaml:"added_columns,omitempty"`
+	RemovedColumns []*Column     `json:"removed_columns,omitempty" yaml:"removed_columns,omitempty"`
+	ChangedColumns []*ColumnDiff `json:"changed_columns,omitempty" yaml:"changed_columns,omitempty"`
+
+	AddedIndexes   []*Index     `json:"added_indexes,omitempty" yaml:"added_indexes,omitempty"`
+	RemovedIndexes []*Index     `json:"removed_indexes,omitempty" yaml:"removed_indexes,omitempty"`
+	ChangedIndexes []*IndexDiff `json:"changed_indexes,omitempty" yaml:"changed_indexes,omitempty"`
+}
+
+// ColumnDiff holds the differences of a column between two schemas.
+type ColumnDiff struct {
+	Name string `json:"name" yaml:"name"`
+
+	From *Column `json:"-" yaml:"-"`
+	To   *Column `json:"-" yaml:"-"`
+
+	Changes []Change `json:"changes" yaml:"changes"`
+}
+
+// IndexDiff holds the differences of an index between two schemas.
+// Indexes are matched by the columns they index.
+type IndexDiff struct {
+	Name string `json:"name" yaml:"name"`
+
+	From *Index `json:"-" yaml:"-"`
+	To   *Index `json:"-" yaml:"-"`
+
+	Changes []Change `json:"changes" yaml:"changes"`
+}
+
+// Change is a changed field value.
+type Change struct {
+	Field string `json:"field" yaml:"field"`
+	From  string `json:"from" yaml:"from"`
+	To    string `json:"to" yaml:"to"`
+}
+
+// Empty returns true if the schemas are equal.
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Compare returns the differences between the from and to schemas.
+// Tables and columns are matched by name, indexes by the indexed columns.
+func Compare(from, to []*Table) *Diff {
+	result := &Diff{}
+
+	fromTables := map[string]*Table{}
+	for _, table := range from {
+		fromTables[table.Name] = table
+	}
+	toTables := map[string]*Table{}
+	for _, table := range to {
+		toTables[table.Name] = table
+		if _, ok := fromTables[table.Name]; !ok {
+			result.Added = append(result.Added, table)
+		}
+	}
+
+	for _, table := range from {
+		target, ok := toTables[table.Name]
+		if !ok {
+			result.Removed = append(result.Removed, table)
+			continue
+		}
+		if diff := compareTable(table, target); diff != nil {
+			result.Changed = append(result.Changed, diff)
+		}
+	}
+	return result
+}
+
+func compareTable(from, to *Table) *TableDiff {
+	result := &TableDiff{
+		Name: from.Name,
+		From: from,
+		To:   to,
+	}
+	result.Changes = compareFields([][3]string{
+		{"comment", from.Comment, to.Comment},
+	})
+
+	fromColumns := map[string]*Column{}
+	for _, column := range from.Columns {
+		fromColumns[column.Name] = column
+	}
+	toColumns := map[string]*Column{}
+	for _, column := range to.Columns {
+		toColumns[column.Name] = column
+		if _, ok := fromColumns[column.Name]; !ok {
+			result.AddedColumns = append(result.AddedColumns, column)
+		}
+	}
+	for _, column := range from.Columns {
+		target, ok := toColumns[column.Name]
+		if !ok {
+			result.RemovedColumns = append(result.RemovedColumns, column)
+			continue
+		}
+		changes := compareFields([][3]string{
+			{"type", column.Type, target.Type},
+			{"datatype", column.DataType, target.DataType},
+			{"size", fmt.Sprint(column.Size), fmt.Sprint(target.Size)},
+			{"scale", fmt.Sprint(column.Scale), fmt.Sprint(target.Scale)},
+			{"key", column.Key, target.Key},
+			{"comment", column.Comment, target.Comment},
+			{"values", strings.Join(column.Values, ","), strings.Join(target.Values, ",")},
+		})
+		if len(changes) > 0 {
+			result.ChangedColumns = append(result.ChangedColumns, &ColumnDiff{
+				Name:    column.Name,
+				From:    column,
+				To:      target,
+				Changes: changes,
+			})
+		}
+	}
+
+	fromIndexes := map[string]*Index{}
+	for _, index := range from.Indexes {
+		fromIndexes[index.key()] = index
+	}
+	toIndexes := map[string]*Index{}
+	for _, index := range to.Indexes {
+		toIndexes[index.key()] = index
+		if _, ok := fromIndexes[index.key()]; !ok {
+			result.AddedIndexes = append(result.AddedIndexes, index)
+		}
+	}
+	for _, index := range from.Indexes {
+		target, ok := toIndexes[index.key()]
+		if !ok {
+			result.RemovedIndexes = append(result.RemovedIndexes, index)
+			continue
+		}
+		changes := compareFields([][3]string{
+			{"unique", fmt.Sprint(index.Unique), fmt.Sprint(target.Unique)},
+		})
+		if len(changes) > 0 {
+			result.ChangedIndexes = append(result.ChangedIndexes, &IndexDiff{
+				Name:    index.String(),
+				From:    index,
+				To:      target,
+				Changes: changes,
+			})
+		}
+	}
+
+	if len(result.Changes) == 0 &&
+		len(result.AddedColumns) == 0 && len(result.RemovedColumns) == 0 && len(result.ChangedColumns) == 0 &&
+		len(result.AddedIndexes) == 0 && len(result.RemovedIndexes) == 0 && len(result.ChangedIndexes) == 0 {
+		return nil
+	}
+	return result
+}
+
+// compareFields returns changes for each (field, from, to) tuple with different values.
+func compareFields(fields [][3]string) []Change {
+	var result []Change
+	for _, field := range fields {
+		if field[1] != field[2] {
+			result = append(result, Change{
+				Field: field[0],
+				From:  field[1],
+				To:    field[2],
+			})
+		}
+	}
+	return result
+}
+
+// key identifies an index by the primary flag and the indexed columns.
+func (i *Index) key() string {
+	return fmt.Sprintf("%t:%s", i.Primary, strings.Join(i.Columns, ","))
+}
+
+// String returns a readable index description, e.g. `idx_name (a, b) unique`.
+func (i *Index) String() string {
+	result := fmt.Sprintf("(%s)", strings.Join(i.Columns, ", "))
+	switch {
+	case i.Primary:
+		result = "primary key " + result
+	case i.Unique:
+		result += " unique"
+	}
+	if i.Name != "" && !i.Primary {
+		result = i.Name + " " + result
+	}
+	return result
+}
+
+// String returns the differences as readable text, one change per line.
+// Added lines are prefixed with `+`, removed with `-` and changed with `~`.
+func (d *Diff) String() string {
+	var lines []string
+	add := func(indent int, format string, args ...interface{}) {
+		lines = append(lines, strings.Repeat("  ", indent)+fmt.Sprintf(format, args...))
+	}
+
+	for _, table := range d.Added {
+		add(0, "+ table %s", table.Name)
+	}
+	for _, table := range d.Removed {
+		add(0, "- table %s", table.Name)
+	}
+	for _, table := range d.Changed {
+		add(0, "~ table %s", table.Name)
+		for _, change := range table.Changes {
+			add(1, "~ %s", change)
+		}
+		for _, column := range table.AddedColumns {
+			add(1, "+ column %s %s", column.Name, columnType(column))
+		}
+		for _, column := range table.RemovedColumns {
+			add(1, "- column %s %s", column.Name, columnType(column))
+		}
+		for _, column := range table.ChangedColumns {
+			changes := []string{}
+			for _, change := range column.Changes {
+				changes = append(changes, change.String())
+			}
+			add(1, "~ column %s: %s", column.Name, strings.Join(changes, ", "))
+		}
+		for _, index := range table.AddedIndexes {
+			add(1, "+ index %s", index)
+		}
+		for _, index := range table.RemovedIndexes {
+			add(1, "- index %s", index)
+		}
+		for _, index := range table.ChangedIndexes {
+			changes := []string{}
+			for _, change := range index.Changes {
+				changes = append(changes, change.String())
+			}
+			add(1, "~ index %s: %s", index.Name, strings.Join(changes, ", "))
+		}
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// String returns the change as `field: from -> to`.
+func (c Change) String() string {
+	quote := func(s string) string {
+		if s == "" || strings.ContainsAny(s, " ,") {
+			return fmt.Sprintf("%q", s)
+		}
+		return s
+	}
+	return fmt.Sprintf("%s %s -> %s", c.Field, quote(c.From), quote(c.To))
+}
+
+func columnType(column *Column) string {
+	if column.DataType != "" {
+		return column.DataType
+	}
+	if len(column.Values) > 0 {
+		return "enum(" + strings.Join(slices.Clone(column.Values), ",") + ")"
+	}
+	return column.Type
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	users := func(modify func(*Table)) *Table {
+		table := &Table{
+			Name:    "users",
+			Comment: "Users",
+			Columns: []*Column{
+				{Name: "id", Type: "integer", DataType: "bigint", Size: 8, Key: "PRI"},
+				{Name: "email", Type: "text", DataType: "varchar", Size: 255},
+			},
+			Indexes: []*Index{
+				{Name: "users_pkey", Columns: []string{"id"}, Primary: true},
+				{Name: "users_email", Columns: []string{"email"}},
+			},
+		}
+		if modify != nil {
+			modify(table)
+		}
+		return table
+	}
+
+	testCases := []struct {
+		name string
+		from []*Table
+		to   []*Table
+		want string
+	}{
+		{
+			name: "equal",
+			from: []*Table{users(nil)},
+			to:   []*Table{users(nil)},
+			want: "",
+		},
+		{
+			name: "added table",
+			from: nil,
+			to:   []*Table{users(nil)},
+			want: "+ table users\n",
+		},
+		{
+			name: "removed table",
+			from: []*Table{users(nil)},
+			to:   nil,
+			want: "- table users\n",
+		},
+		{
+			name: "table comment",
+			from: []*Table{users(nil)},
+			to: []*Table{users(func(table *Table) {
+				table.Comment = "Registered users"
+			})},
+			want: "~ table users\n  ~ comment Users -> \"Registered users\"\n",
+		},
+		{
+			name: "added column",
+			from: []*Table{users(nil)},
+			to: []*Table{users(func(table *Table) {
+				table.Columns = append(table.Columns, &Column{Name: "created_at", Type: "timestamp", DataType: "datetime"})
+			})},
+			want: "~ table users\n  + column created_at datetime\n",
+		},
+		{
+			name: "removed column",
+			from: []*Table{users(nil)},
+			to: []*Table{users(func(table *Table) {
+				table.Columns = table.Columns[:1]
+			})},
+			want: "~ table users\n  - column email varchar\n",
+		},
+		{
+			name: "changed column",
+			from: []*Table{users(nil)},
+			to: []*Table{users(func(table *Table) {
+				table.Columns[1] = &Column{Name: "email", Type: "text", DataType: "varchar", Size: 320, Comment: "Login email"}
+			})},
+			want: "~ table users\n  ~ column email: size 255 -> 320, comment \"\" -> \"Login email\"\n",
+		},
+		{
+			name: "changed enum values",
+			from: []*Table{{Name: "events", Columns: []*Column{{Name: "kind", Type: "enum", Values: []string{"click"}}}}},
+			to:   []*Table{{Name: "events", Columns: []*Column{{Name: "kind", Type: "enum", Values: []string{"click", "view"}}}}},
+			want: "~ table events\n  ~ column kind: values click -> \"click,view\"\n",
+		},
+		{
+			name: "added and removed index",
+			from: []*Table{users(nil)},
+			to: []*Table{users(func(table *Table) {
+				table.Indexes[1] = &Index{Name: "users_id_email", Columns: []string{"id", "email"}}
+			})},
+			want: "~ table users\n  + index users_id_email (id, email)\n  - index users_email (email)\n",
+		},
+		{
+			name: "changed index",
+			from: []*Table{users(nil)},
+			to: []*Table{users(func(table *Table) {
+				table.Indexes[1] = &Index{Name: "users_email", Columns: []string{"email"}, Unique: true}
+			})},
+			want: "~ table users\n  ~ index users_email (email): unique false -> true\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			diff := Compare(tc.from, tc.to)
+			require.Equal(t, tc.want, diff.String())
+			require.Equal(t, tc.want == "", diff.Empty())
+		})
+	}
+}
+
+func TestCompareChanges(t *testing.T) {
+	from := []*Table{{
+		Name:    "prices",
+		Columns: []*Column{{Name: "amount", Type: "decimal", DataType: "decimal", Size: 10, Scale: 2}},
+	}}
+	to := []*Table{{
+		Name:    "prices",
+		Columns: []*Column{{Name: "amount", Type: "decimal", DataType: "decimal", Size: 12, Scale: 4}},
+	}}
+
+	diff := Compare(from, to)
+	require.Len(t, diff.Changed, 1)
+
+	table := diff.Changed[0]
+	require.Same(t, from[0], table.From)
+	require.Same(t, to[0], table.To)
+	require.Len(t, table.ChangedColumns, 1)
+
+	column := table.ChangedColumns[0]
+	require.Same(t, from[0].Columns[0], column.From)
+	require.Same(t, to[0].Columns[0], column.To)
+	require.Equal(t, []Change{
+		{Field: "size", From: "10", To: "12"},
+		{Field: "scale", From: "2", To: "4"},
+	}, column.Changes)
+}
@@ -38,7 +38,8 @@ func (t *Table) Ignore() bool {
 var TableFields = []string{"TABLE_NAME", "TABLE_COMMENT"}
 
 // Column represents a database column with its metadata.
-// SIZE is filled from sql query on postgres.
+// SIZE is filled from sql query on postgres. For decimal columns,
+// Size and Scale hold the precision and the scale.
 type Column struct {
 	Name     string   `db:"COLUMN_NAME" json:"name" yaml:"name"`
 	Type     string   `db:"COLUMN_TYPE" json:"type,omitempty" yaml:"type,omitempty"`
@@ -46,7 +47,14 @@ type Column struct {
 	Comment  string   `db:"COLUMN_COMMENT" json:"comment,omitempty" yaml:"comment,omitempty"`
 	DataType string   `db:"DATA_TYPE" json:"datatype,omitempty" yaml:"datatype,omitempty"`
 	Size     int      `db:"SIZE" json:"size,omitempty" yaml:"size,omitempty"`
+	Scale    int      `db:"SCALE" json:"scale,omitempty" yaml:"scale,omitempty"`
 	Values   []string `json:"values,omitempty" yaml:"values,omitempty"`
+
+	// Nullable and Default are set by portable migrations and desired
+	// schemas, introspection doesn't fill them. A nil Nullable keeps
+	// the database default, which allows NULL values.
+	Nullable *bool   `db:"-" json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	Default  *string `db:"-" json:"default,omitempty" yaml:"default,omitempty"`
 }
 
 // ColumnFields lists the database columns queried from Column (mysql, omits SIZE).